@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ArchiveMonth is one month of posts on the archive page.
+type ArchiveMonth struct {
+	Month int
+	Count int
+	Posts []Post
+}
+
+// ArchiveYear is one year of posts on the archive page, months newest first.
+type ArchiveYear struct {
+	Year   int
+	Count  int
+	Months []ArchiveMonth
+}
+
+// buildArchive groups date-sorted posts by year and month. Posts whose date
+// does not parse are left out.
+func buildArchive(posts []Post) []ArchiveYear {
+	var years []ArchiveYear
+	for _, p := range posts {
+		t := p.Time()
+		if t.IsZero() {
+			continue
+		}
+		if len(years) == 0 || years[len(years)-1].Year != t.Year() {
+			years = append(years, ArchiveYear{Year: t.Year()})
+		}
+		y := &years[len(years)-1]
+		if len(y.Months) == 0 || y.Months[len(y.Months)-1].Month != int(t.Month()) {
+			y.Months = append(y.Months, ArchiveMonth{Month: int(t.Month())})
+		}
+		m := &y.Months[len(y.Months)-1]
+		m.Posts = append(m.Posts, p)
+		m.Count++
+		y.Count++
+	}
+	return years
+}
+
+func (g *Generator) renderArchive(site *Site) error {
+	tmpl, err := g.templates.Page("archive")
+	if err != nil {
+		return err
+	}
+	outPath := filepath.Join(g.siteDir(site), "archive.html")
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("创建 %s: %w", outPath, err)
+	}
+	defer f.Close()
+
+	years := buildArchive(site.Posts)
+	total := 0
+	for _, y := range years {
+		total += y.Count
+	}
+	ctx := map[string]interface{}{
+		"Site":  site,
+		"Title": site.Lang.T("archive"),
+		"Years": years,
+		"Total": total,
+	}
+	if err := tmpl.Execute(f, ctx); err != nil {
+		return fmt.Errorf("渲染归档页: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Author is someone posts are credited to, from the authors file. Posts
+// name their authors by ID in the authors front matter field.
+type Author struct {
+	ID      string `toml:"id"`
+	Name    string `toml:"name"`
+	Avatar  string `toml:"avatar"`
+	GitHub  string `toml:"github"`
+	Website string `toml:"website"`
+	Bio     string `toml:"bio"`
+
+	// Posts lists the author's posts in the site's language, newest first.
+	Posts []Post `toml:"-"`
+
+	// Line is where the author's [[authors]] table starts in the file.
+	Line int `toml:"-"`
+
+	dir string
+}
+
+// URL is the author's page listing their posts.
+func (a Author) URL() string {
+	return "/" + a.dir + "/" + a.ID + ".html"
+}
+
+// Homepage is the author's website, else their GitHub profile.
+func (a Author) Homepage() string {
+	if a.Website != "" {
+		return a.Website
+	}
+	if a.GitHub != "" {
+		return "https://github.com/" + a.GitHub
+	}
+	return ""
+}
+
+// loadAuthors reads the authors file, if there is one, recording the line
+// of each entry so diagnostics can point at it.
+func loadAuthors(path string) ([]Author, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var authorsConfig struct {
+		Authors []Author `toml:"authors"`
+	}
+	if _, err := toml.Decode(string(data), &authorsConfig); err != nil {
+		return nil, fmt.Errorf("解析 %s: %w", path, err)
+	}
+
+	lines := tableLines(data, "authors")
+	authors := authorsConfig.Authors
+	if len(lines) == len(authors) {
+		for i := range authors {
+			authors[i].Line = lines[i]
+		}
+	}
+	return authors, nil
+}
+
+// validateAuthors reports entries without a usable ID or a name, repeated
+// IDs and unusable avatars.
+func (g *Generator) validateAuthors(authors []Author) {
+	file := g.cfg.AuthorsFile
+	seen := make(map[string]Author)
+	for _, a := range authors {
+		if a.ID == "" || strings.ContainsAny(a.ID, `/\ .`) {
+			g.diags.Errorf(file, a.Line, "作者 ID 无效: %q", a.ID)
+			continue
+		}
+		if strings.TrimSpace(a.Name) == "" {
+			g.diags.Errorf(file, a.Line, "作者 %s 缺少名称 (name)", a.ID)
+		}
+		if a.Avatar != "" {
+			if !validAssetURL(a.Avatar) {
+				g.diags.Errorf(file, a.Line, "作者 %s 的头像地址无效: %q", a.ID, a.Avatar)
+			}
+		}
+		if prev, ok := seen[a.ID]; ok {
+			g.diags.Errorf(file, a.Line, "作者 %s 与第 %d 行重复", a.ID, prev.Line)
+			continue
+		}
+		seen[a.ID] = a
+	}
+}
+
+// assignAuthors credits posts without an authors field to DefaultAuthor,
+// when the authors file lists it, and reports authors the file does not.
+func (g *Generator) assignAuthors(entries []Post, authors []Author) {
+	known := make(map[string]bool)
+	for _, a := range authors {
+		known[a.ID] = true
+	}
+	for i := range entries {
+		p := &entries[i]
+		if len(p.Authors) == 0 && p.Kind == kindPost && known[g.cfg.DefaultAuthor] {
+			p.Authors = []string{g.cfg.DefaultAuthor}
+		}
+		for _, id := range p.Authors {
+			if !known[id] {
+				g.diags.Errorf(p.File, 0, "未知的作者 %q, 请在 %s 中添加", id, g.cfg.AuthorsFile)
+			}
+		}
+	}
+}
+
+// buildAuthors lists the authors with posts in the given sections, in
+// authors file order, each with their posts.
+func buildAuthors(authors []Author, sections []Section, dir string) []Author {
+	var result []Author
+	for _, a := range authors {
+		a.dir = dir
+		a.Posts = nil
+		for _, section := range sections {
+			for _, p := range section.Posts {
+				if p.hasAuthor(a.ID) {
+					a.Posts = append(a.Posts, p)
+				}
+			}
+		}
+		if len(a.Posts) > 0 {
+			result = append(result, a)
+		}
+	}
+	return result
+}
+
+func (p Post) hasAuthor(id string) bool {
+	for _, a := range p.Authors {
+		if a == id {
+			return true
+		}
+	}
+	return false
+}
+
+// authorsOf returns the authors of post, in front matter order.
+func (s *Site) authorsOf(post Post) []Author {
+	var authors []Author
+	for _, id := range post.Authors {
+		for _, a := range s.Authors {
+			if a.ID == id {
+				authors = append(authors, a)
+			}
+		}
+	}
+	return authors
+}
+
+// Author looks an author up by ID for templates, such as the profile card:
+// {{with .Site.Author "yumosx"}}. Unlike Site.Authors it includes authors
+// without posts; it is nil for unknown IDs.
+func (s *Site) Author(id string) *Author {
+	for i := range s.allAuthors {
+		if s.allAuthors[i].ID == id {
+			return &s.allAuthors[i]
+		}
+	}
+	return nil
+}
+
+// renderAuthors writes a page per author listing their posts.
+func (g *Generator) renderAuthors(site *Site) error {
+	if len(site.Authors) == 0 {
+		return nil
+	}
+	dir := filepath.Join(g.siteDir(site), g.cfg.AuthorsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpl, err := g.templates.Page("author")
+	if err != nil {
+		return err
+	}
+	for _, author := range site.Authors {
+		ctx := map[string]interface{}{
+			"Site":   site,
+			"Title":  site.Lang.T("posts_by", author.Name),
+			"Author": author,
+		}
+		outPath := filepath.Join(dir, author.ID+".html")
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("创建 %s: %w", outPath, err)
+		}
+		if err := tmpl.Execute(f, ctx); err != nil {
+			f.Close()
+			return fmt.Errorf("渲染作者 %s: %w", author.ID, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("关闭 %s: %w", outPath, err)
+		}
+	}
+	return nil
+}
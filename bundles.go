@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// bundleIndex is the markdown file that turns a content subdirectory into a
+// page bundle: content/<slug>/index.md plus any sibling resources.
+const bundleIndex = "index.md"
+
+// bundleBaseKey carries the public URL directory of the post being
+// converted, so relative links inside a bundle resolve next to it.
+var bundleBaseKey = parser.NewContextKey()
+
+// bundleResources lists every non-markdown file inside a bundle directory,
+// relative to it and slash-separated.
+func bundleResources(dir string) ([]string, error) {
+	var resources []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) == ".md" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, filepath.ToSlash(rel))
+		return nil
+	})
+	return resources, err
+}
+
+// copyBundleResources copies a post's sibling files into dstDir.
+func copyBundleResources(post Post, dstDir string) error {
+	for _, rel := range post.Resources {
+		data, err := os.ReadFile(filepath.Join(post.BundleDir, filepath.FromSlash(rel)))
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return fmt.Errorf("复制 %s: %w", dst, err)
+		}
+	}
+	return nil
+}
+
+// bundleLinkTransformer rewrites relative link and image destinations
+// against the bundle's public URL directory.
+type bundleLinkTransformer struct{}
+
+func (bundleLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	base, _ := pc.Get(bundleBaseKey).(string)
+	if base == "" {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Image:
+			n.Destination = resolveBundleURL(base, n.Destination)
+		case *ast.Link:
+			n.Destination = resolveBundleURL(base, n.Destination)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// resolveBundleURL joins a relative destination onto base, leaving absolute
+// URLs, rooted paths and same-page fragments untouched.
+func resolveBundleURL(base string, dest []byte) []byte {
+	u, err := url.Parse(string(dest))
+	if err != nil || u.IsAbs() || u.Host != "" || u.Path == "" || strings.HasPrefix(u.Path, "/") {
+		return dest
+	}
+	u.Path = path.Join(base, u.Path)
+	return []byte(u.String())
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// linkProblem is one reference in the rendered site that does not resolve.
+type linkProblem struct {
+	File   string
+	Line   int
+	Ref    string
+	Reason string
+}
+
+func (p linkProblem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Ref, p.Reason)
+}
+
+// pageRef is an href/src value and the line it appears on.
+type pageRef struct {
+	Line  int
+	Value string
+}
+
+// checkedPage holds what the checker needs from one rendered HTML file.
+type checkedPage struct {
+	IDs  map[string]bool
+	Refs []pageRef
+}
+
+// linkChecker validates references inside PublicDir. External links are only
+// probed when client is non-nil.
+type linkChecker struct {
+	publicDir string
+	baseHost  string
+	client    *http.Client
+	pages     map[string]*checkedPage // slash path relative to publicDir
+}
+
+// checkCommand implements `check [-external] [-timeout d]`, validating the
+// already rendered PublicDir and failing when anything is broken.
+func checkCommand(cfg Config, args []string) error {
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	external := fs.Bool("external", false, "同时检查外部链接")
+	timeout := fs.Duration("timeout", cfg.CheckTimeout, "外部链接请求超时")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	c := &linkChecker{
+		publicDir: cfg.PublicDir,
+		pages:     make(map[string]*checkedPage),
+	}
+	if u, err := url.Parse(cfg.BaseURL); err == nil {
+		c.baseHost = u.Host
+	}
+	if *external {
+		client, err := newProbeClient(cfg.CheckProxy, *timeout)
+		if err != nil {
+			return err
+		}
+		c.client = client
+	}
+
+	problems, err := c.Run()
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("发现 %d 个失效链接", len(problems))
+	}
+	fmt.Println("链接检查通过")
+	return nil
+}
+
+// newProbeClient builds the HTTP client for external probes, optionally
+// routed through proxy so CI can point it at a local stand-in.
+func newProbeClient(proxy string, timeout time.Duration) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("解析 check_proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// Run parses every HTML file under publicDir and reports unresolved
+// references sorted by file and line.
+func (c *linkChecker) Run() ([]linkProblem, error) {
+	if _, err := os.Stat(c.publicDir); err != nil {
+		return nil, fmt.Errorf("找不到输出目录 %s，请先生成站点", c.publicDir)
+	}
+	err := filepath.WalkDir(c.publicDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".html" {
+			return err
+		}
+		rel, err := filepath.Rel(c.publicDir, p)
+		if err != nil {
+			return err
+		}
+		page, err := parseCheckedPage(p)
+		if err != nil {
+			return fmt.Errorf("解析 %s: %w", p, err)
+		}
+		c.pages[filepath.ToSlash(rel)] = page
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []linkProblem
+	external := make(map[string][]linkProblem)
+	for rel, page := range c.pages {
+		for _, ref := range page.Refs {
+			loc := linkProblem{File: filepath.Join(c.publicDir, filepath.FromSlash(rel)), Line: ref.Line, Ref: ref.Value}
+			target, isExternal, ok := c.resolve(rel, ref.Value)
+			if !ok {
+				continue
+			}
+			if isExternal {
+				external[target] = append(external[target], loc)
+				continue
+			}
+			if reason := c.checkInternal(target); reason != "" {
+				loc.Reason = reason
+				problems = append(problems, loc)
+			}
+		}
+	}
+	if c.client != nil {
+		problems = append(problems, c.checkExternal(external)...)
+	}
+
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
+
+// resolve turns a reference found on page into either an absolute external
+// URL or a site path with optional fragment. ok is false for references
+// that are not checkable, such as mailto: links.
+func (c *linkChecker) resolve(page, ref string) (target string, external, ok bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", false, false
+	}
+	switch u.Scheme {
+	case "", "http", "https":
+	default:
+		return "", false, false
+	}
+	// Protocol-relative references (//host/x) have a host but no scheme.
+	if u.Host != "" && u.Host != c.baseHost {
+		if u.Scheme == "" {
+			u.Scheme = "https"
+		}
+		u.Fragment = ""
+		return u.String(), true, true
+	}
+	if u.Host == "" && u.Path == "" && u.Fragment == "" {
+		return "", false, false
+	}
+
+	p := u.Path
+	switch {
+	case p == "":
+		p = "/" + page
+	case !strings.HasPrefix(p, "/"):
+		p = path.Join("/"+path.Dir(page), p)
+		if strings.HasSuffix(u.Path, "/") {
+			p += "/"
+		}
+	}
+	if u.Fragment != "" {
+		p += "#" + u.Fragment
+	}
+	return p, false, true
+}
+
+// checkInternal returns why a site path does not resolve, or "" if it does.
+func (c *linkChecker) checkInternal(target string) string {
+	p, fragment, _ := strings.Cut(target, "#")
+	rel := strings.TrimPrefix(path.Clean(p), "/")
+	if strings.HasSuffix(p, "/") || rel == "" || rel == "." {
+		rel = path.Join(rel, "index.html")
+	}
+	info, err := os.Stat(filepath.Join(c.publicDir, filepath.FromSlash(rel)))
+	if err == nil && info.IsDir() {
+		rel = path.Join(rel, "index.html")
+		_, err = os.Stat(filepath.Join(c.publicDir, filepath.FromSlash(rel)))
+	}
+	if err != nil {
+		return "目标不存在"
+	}
+	if fragment == "" {
+		return ""
+	}
+	page, ok := c.pages[rel]
+	if !ok {
+		return ""
+	}
+	if !page.IDs[fragment] {
+		return fmt.Sprintf("锚点 #%s 不存在", fragment)
+	}
+	return ""
+}
+
+// checkExternal probes each distinct external URL once, in parallel.
+func (c *linkChecker) checkExternal(targets map[string][]linkProblem) []linkProblem {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		problems []linkProblem
+		sem      = make(chan struct{}, 8)
+	)
+	for target, locs := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			reason := c.probe(target)
+			<-sem
+			if reason == "" {
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, loc := range locs {
+				loc.Reason = reason
+				problems = append(problems, loc)
+			}
+		}()
+	}
+	wg.Wait()
+	return problems
+}
+
+// probe issues a HEAD request, retrying with GET for servers that reject
+// HEAD, and returns a reason when the URL looks dead.
+func (c *linkChecker) probe(target string) string {
+	resp, err := c.client.Head(target)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.client.Get(target)
+	}
+	if err != nil {
+		return fmt.Sprintf("请求失败: %v", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+	return ""
+}
+
+// refAttrs lists the attributes holding a URL, per element.
+var refAttrs = map[string][]string{
+	"a":      {"href"},
+	"link":   {"href"},
+	"area":   {"href"},
+	"img":    {"src", "srcset"},
+	"source": {"src", "srcset"},
+	"script": {"src"},
+	"iframe": {"src"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+}
+
+func parseCheckedPage(file string) (*checkedPage, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	page := &checkedPage{IDs: make(map[string]bool)}
+	z := html.NewTokenizer(f)
+	line := 1
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() == io.EOF {
+				return page, nil
+			}
+			return nil, z.Err()
+		}
+		tokenLine := line
+		line += strings.Count(string(z.Raw()), "\n")
+		if tt != html.StartTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		tok := z.Token()
+		for _, a := range tok.Attr {
+			switch {
+			case a.Key == "id" || (tok.Data == "a" && a.Key == "name"):
+				page.IDs[a.Val] = true
+			case a.Key == "srcset" && slices.Contains(refAttrs[tok.Data], a.Key):
+				for _, candidate := range strings.Split(a.Val, ",") {
+					if fields := strings.Fields(candidate); len(fields) > 0 {
+						page.Refs = append(page.Refs, pageRef{Line: tokenLine, Value: fields[0]})
+					}
+				}
+			case slices.Contains(refAttrs[tok.Data], a.Key):
+				page.Refs = append(page.Refs, pageRef{Line: tokenLine, Value: a.Val})
+			}
+		}
+	}
+}
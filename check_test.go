@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	c := &linkChecker{baseHost: "example.com"}
+	tests := []struct {
+		page, ref string
+		target    string
+		external  bool
+		ok        bool
+	}{
+		{"index.html", "/posts/a.html", "/posts/a.html", false, true},
+		{"posts/a.html", "b.html", "/posts/b.html", false, true},
+		{"posts/a.html", "../archive.html#2025", "/archive.html#2025", false, true},
+		{"posts/a.html", "bundle/", "/posts/bundle/", false, true},
+		{"posts/a.html", "#top", "/posts/a.html#top", false, true},
+		{"index.html", "https://example.com/feed.xml", "/feed.xml", false, true},
+		{"index.html", "//example.com/feed.xml", "/feed.xml", false, true},
+		{"index.html", "https://other.org/x#frag", "https://other.org/x", true, true},
+		{"index.html", "//cdn.other.org/lib.js", "https://cdn.other.org/lib.js", true, true},
+		{"index.html", "mailto:me@example.com", "", false, false},
+		{"index.html", "ftp://other.org/file", "", false, false},
+		{"index.html", "", "", false, false},
+	}
+	for _, tt := range tests {
+		target, external, ok := c.resolve(tt.page, tt.ref)
+		if target != tt.target || external != tt.external || ok != tt.ok {
+			t.Errorf("resolve(%q, %q) = %q, %v, %v, want %q, %v, %v",
+				tt.page, tt.ref, target, external, ok, tt.target, tt.external, tt.ok)
+		}
+	}
+}
+
+func TestCheckInternal(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"index.html":              `<h1 id="top">home</h1>`,
+		"posts/a.html":            `<h2 id="intro">a</h2><a name="old">x</a>`,
+		"posts/bundle/index.html": `<p id="p">bundle</p>`,
+		"static/style.css":        `body {}`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := &linkChecker{publicDir: dir, pages: make(map[string]*checkedPage)}
+	if _, err := c.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		ok     bool
+	}{
+		{"/", true},
+		{"/#top", true},
+		{"/#missing", false},
+		{"/posts/a.html", true},
+		{"/posts/a.html#intro", true},
+		{"/posts/a.html#old", true},
+		{"/posts/a.html#nope", false},
+		{"/posts/b.html", false},
+		{"/posts/bundle/", true},
+		{"/posts/bundle", true},
+		{"/posts/bundle/#p", true},
+		{"/posts/", false},
+		{"/static/style.css", true},
+		{"/static/style.css#x", true},
+	}
+	for _, tt := range tests {
+		if reason := c.checkInternal(tt.target); (reason == "") != tt.ok {
+			t.Errorf("checkInternal(%q) = %q, want ok %v", tt.target, reason, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// dataDecoders parses data files by extension into generic values.
+var dataDecoders = map[string]func([]byte, *interface{}) error{
+	".toml": func(b []byte, v *interface{}) error {
+		var m map[string]interface{}
+		if err := toml.Unmarshal(b, &m); err != nil {
+			return err
+		}
+		*v = m
+		return nil
+	},
+	".yaml": func(b []byte, v *interface{}) error { return yaml.Unmarshal(b, v) },
+	".yml":  func(b []byte, v *interface{}) error { return yaml.Unmarshal(b, v) },
+	".json": func(b []byte, v *interface{}) error { return json.Unmarshal(b, v) },
+}
+
+// loadData reads every TOML, YAML and JSON file below dir into a tree keyed
+// by file name without extension, subdirectories becoming nested maps:
+// data/social/github.yaml is .Site.Data.social.github in templates. A missing
+// dir yields an empty tree.
+func loadData(dir string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			sub, err := loadData(p)
+			if err != nil {
+				return nil, err
+			}
+			if err := setData(data, e.Name(), sub, p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ext := strings.ToLower(filepath.Ext(e.Name()))
+		decode, ok := dataDecoders[ext]
+		if !ok {
+			continue
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		var v interface{}
+		if err := decode(content, &v); err != nil {
+			return nil, fmt.Errorf("解析数据文件 %s: %w", p, err)
+		}
+		if err := setData(data, strings.TrimSuffix(e.Name(), filepath.Ext(e.Name())), v, p); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func setData(data map[string]interface{}, key string, v interface{}, file string) error {
+	if _, exists := data[key]; exists {
+		return fmt.Errorf("数据文件 %s: 键 %s 重复定义", file, key)
+	}
+	data[key] = v
+	return nil
+}
+
+// tableLines returns the line of every [[name]] array-of-tables header in a
+// TOML file, in order, so entries decoded from it can be located for
+// diagnostics. Spacing inside the brackets and trailing comments are
+// allowed, as TOML allows them.
+func tableLines(data []byte, name string) []int {
+	var lines []int
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		line = strings.TrimSpace(line)
+		inner, ok := strings.CutPrefix(line, "[[")
+		if !ok {
+			continue
+		}
+		if inner, ok = strings.CutSuffix(inner, "]]"); ok && strings.TrimSpace(inner) == name {
+			lines = append(lines, n)
+		}
+	}
+	return lines
+}
+
+// validAssetURL reports whether an image URL from a data file is usable: a
+// site path or an absolute http(s) URL.
+func validAssetURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (!u.IsAbs() || u.Scheme == "http" || u.Scheme == "https")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTableLines(t *testing.T) {
+	data := `# authors
+[[authors]]
+id = "a"
+
+[[authors]] # the second one
+id = "b"
+
+[[ authors ]]
+id = "c"
+
+[[links]]
+note = "[[authors]] in a string is not a header"
+  [[authors]]
+id = "d"
+`
+	if got, want := tableLines([]byte(data), "authors"), []int{2, 5, 8, 13}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tableLines(authors) = %v, want %v", got, want)
+	}
+	if got, want := tableLines([]byte(data), "links"), []int{11}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tableLines(links) = %v, want %v", got, want)
+	}
+}
+
+func TestValidAssetURL(t *testing.T) {
+	tests := map[string]bool{
+		"/static/me.png":             true,
+		"avatars/me.png":             true,
+		"https://example.com/me.png": true,
+		"http://example.com/me.png":  true,
+		"//example.com/me.png":       true,
+		"javascript:alert(1)":        false,
+		"data:image/png;base64,AAAA": false,
+		"https://exa mple.com/%zz":   false,
+	}
+	for raw, want := range tests {
+		if got := validAssetURL(raw); got != want {
+			t.Errorf("validAssetURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
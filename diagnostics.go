@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Severity ranks a diagnostic. Errors fail the build; warnings only fail it
+// in strict mode.
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Diagnostic is one problem found while loading or rendering. Line is 0
+// when the problem concerns the file as a whole.
+type Diagnostic struct {
+	Severity Severity `json:"severity"`
+	File     string   `json:"file"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	label := "警告"
+	if d.Severity == SeverityError {
+		label = "错误"
+	}
+	pos := d.File
+	if d.Line > 0 {
+		pos = fmt.Sprintf("%s:%d", d.File, d.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", pos, label, d.Message)
+}
+
+// diagnostics collects the problems of one build in the order they were
+// reported, so a build can surface all of them instead of the first.
+type diagnostics struct {
+	mu    sync.Mutex
+	items []Diagnostic
+}
+
+func (d *diagnostics) add(sev Severity, file string, line int, format string, args ...interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.items = append(d.items, Diagnostic{
+		Severity: sev,
+		File:     file,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *diagnostics) Warnf(file string, line int, format string, args ...interface{}) {
+	d.add(SeverityWarning, file, line, format, args...)
+}
+
+func (d *diagnostics) Errorf(file string, line int, format string, args ...interface{}) {
+	d.add(SeverityError, file, line, format, args...)
+}
+
+// Counts returns the number of errors and warnings collected so far.
+func (d *diagnostics) Counts() (errors, warnings int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, item := range d.items {
+		if item.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// check fails when the collected diagnostics should stop the build.
+func (d *diagnostics) check(strict bool) error {
+	errors, warnings := d.Counts()
+	if errors > 0 || strict && warnings > 0 {
+		return fmt.Errorf("%d 个错误, %d 个警告", errors, warnings)
+	}
+	return nil
+}
+
+// WriteText prints one diagnostic per line followed by a summary.
+func (d *diagnostics) WriteText(w io.Writer) {
+	d.mu.Lock()
+	for _, item := range d.items {
+		fmt.Fprintln(w, item)
+	}
+	d.mu.Unlock()
+	errors, warnings := d.Counts()
+	if errors+warnings > 0 {
+		fmt.Fprintf(w, "共 %d 个错误, %d 个警告\n", errors, warnings)
+	}
+}
+
+// WriteJSON prints the diagnostics as a JSON array for CI tooling.
+func (d *diagnostics) WriteJSON(w io.Writer) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	items := d.items
+	if items == nil {
+		items = []Diagnostic{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+// buildCommand implements the default build: `[-strict] [-format text|json]`.
+// With -format json the diagnostics are the only thing written to stdout.
+func buildCommand(cfg Config, args []string) error {
+	fset := flag.NewFlagSet("build", flag.ContinueOnError)
+	strict := fset.Bool("strict", false, "有警告时也视为失败")
+	format := fset.String("format", "text", "诊断输出格式: text 或 json")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("未知的输出格式 %q", *format)
+	}
+
+	g := NewGenerator(cfg)
+	g.strict = *strict
+	err := g.Run()
+	if *format == "json" {
+		if jsonErr := g.diags.WriteJSON(os.Stdout); jsonErr != nil {
+			return jsonErr
+		}
+	} else {
+		g.diags.WriteText(os.Stderr)
+	}
+	if err != nil {
+		return fmt.Errorf("生成失败: %w", err)
+	}
+	if *format == "text" {
+		fmt.Println("博客生成成功！请查看 public 目录")
+	}
+	return nil
+}
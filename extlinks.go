@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// externalLinkTransformer marks links leaving the site so they open in a new
+// tab. A link is internal when it has no host, or its host is the BaseURL
+// host or matches an allowlist entry (the entry itself or a subdomain).
+type externalLinkTransformer struct {
+	internalHosts []string
+	icon          bool
+}
+
+func newExternalLinkTransformer(cfg Config) externalLinkTransformer {
+	t := externalLinkTransformer{icon: cfg.ExternalLinkIcon}
+	if u, err := url.Parse(cfg.BaseURL); err == nil && u.Hostname() != "" {
+		t.internalHosts = append(t.internalHosts, strings.ToLower(u.Hostname()))
+	}
+	for _, host := range cfg.ExternalLinkAllowlist {
+		t.internalHosts = append(t.internalHosts, strings.ToLower(strings.TrimPrefix(host, ".")))
+	}
+	return t
+}
+
+func (t externalLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	source := reader.Source()
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			if t.isExternal(string(n.Destination)) {
+				t.decorate(n)
+			}
+		case *ast.AutoLink:
+			if n.AutoLinkType == ast.AutoLinkURL && t.isExternal(string(n.URL(source))) {
+				t.decorate(n)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+func (t externalLinkTransformer) isExternal(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, internal := range t.internalHosts {
+		if host == internal || strings.HasSuffix(host, "."+internal) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t externalLinkTransformer) decorate(n ast.Node) {
+	n.SetAttributeString("target", []byte("_blank"))
+	n.SetAttributeString("rel", []byte("noopener noreferrer"))
+	if t.icon {
+		n.SetAttributeString("class", []byte("external-link"))
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"time"
+)
+
+// rssFeed mirrors the subset of RSS 2.0 the site publishes.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate,omitempty"`
+	Description string `xml:"description"`
+
+	// Updated is when the post last changed; RSS has no element for it.
+	Updated string `xml:"atom:updated,omitempty"`
+
+	// Creators names the post's authors; RSS's own author element wants
+	// an email address.
+	Creators []string `xml:"dc:creator"`
+}
+
+// writeFeed writes an RSS 2.0 feed of posts, which are expected newest
+// first, to path. link is the site path of the listing the feed mirrors.
+func (g *Generator) writeFeed(site *Site, path, title, link string, posts []Post) error {
+	channel := rssChannel{
+		Title:       title,
+		Link:        g.absURL(link),
+		Description: title,
+	}
+	for _, p := range posts {
+		item := rssItem{
+			Title:       p.Title,
+			Link:        g.absURL(p.URL()),
+			GUID:        g.absURL(p.URL()),
+			Description: string(p.Content),
+		}
+		for _, a := range site.authorsOf(p) {
+			item.Creators = append(item.Creators, a.Name)
+		}
+		if t := p.Time(); !t.IsZero() {
+			item.PubDate = t.Format(time.RFC1123Z)
+		}
+		if !p.Lastmod.IsZero() {
+			item.Updated = p.Lastmod.Format(time.RFC3339)
+		}
+		channel.Items = append(channel.Items, item)
+	}
+	// The feed changed when any of its posts last did, edits included.
+	if t := newestLastmod(posts); !t.IsZero() {
+		channel.LastBuildDate = t.Format(time.RFC1123Z)
+	}
+
+	out, err := xml.MarshalIndent(rssFeed{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func (g *Generator) blogTemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"now":         time.Now,
+		"extractHost": extractHostFromURL,
+		"formatDate":  formatDate,
+		"absURL":      g.absURL,
+		"relURL":      g.relURL,
+		"truncate":    truncate,
+		"markdownify": g.markdownify,
+		"slugify":     slugify,
+		"where":       where,
+		"sortBy":      sortBy,
+		"groupByYear": groupByYear,
+		"safeHTML":    safeHTML,
+		"jsonify":     jsonify,
+	}
+}
+
+// postDateLayout accepts both zero-padded and bare month/day, since front
+// matter in content/ uses either (2025-09-02 and 2025-9-2).
+const postDateLayout = "2006-1-2"
+
+func parsePostDate(s string) (time.Time, error) {
+	return time.Parse(postDateLayout, strings.TrimSpace(s))
+}
+
+var (
+	zhWeekdays = strings.NewReplacer(
+		"Monday", "星期一", "Tuesday", "星期二", "Wednesday", "星期三",
+		"Thursday", "星期四", "Friday", "星期五", "Saturday", "星期六", "Sunday", "星期日",
+		"Mon", "周一", "Tue", "周二", "Wed", "周三", "Thu", "周四", "Fri", "周五", "Sat", "周六", "Sun", "周日",
+	)
+	zhMonths = strings.NewReplacer(
+		"January", "一月", "February", "二月", "March", "三月", "April", "四月",
+		"May", "五月", "June", "六月", "July", "七月", "August", "八月",
+		"September", "九月", "October", "十月", "November", "十一月", "December", "十二月",
+	)
+)
+
+// formatDate formats a time.Time or front matter date string with a Go
+// layout, translating weekday and month names to Chinese. The layout "zh"
+// is shorthand for 2006年1月2日. Unparseable strings are returned as is.
+func formatDate(layout string, date interface{}) string {
+	var t time.Time
+	switch d := date.(type) {
+	case time.Time:
+		t = d
+	case string:
+		parsed, err := parsePostDate(d)
+		if err != nil {
+			return d
+		}
+		t = parsed
+	default:
+		return fmt.Sprint(date)
+	}
+	if layout == "zh" {
+		return t.Format("2006年1月2日")
+	}
+	out := zhMonths.Replace(zhWeekdays.Replace(t.Format(layout)))
+	if strings.Contains(layout, "Jan") && !strings.Contains(layout, "January") {
+		out = strings.Replace(out, t.Format("Jan"), fmt.Sprintf("%d月", t.Month()), 1)
+	}
+	return out
+}
+
+// absURL resolves a site path against BaseURL.
+func (g *Generator) absURL(p string) string {
+	if u, err := url.Parse(p); err == nil && u.IsAbs() {
+		return p
+	}
+	return strings.TrimSuffix(g.cfg.BaseURL, "/") + "/" + strings.TrimPrefix(p, "/")
+}
+
+// relURL turns a site path into a root-relative URL, keeping any path prefix
+// BaseURL has (for sites served from a subdirectory).
+func (g *Generator) relURL(p string) string {
+	if u, err := url.Parse(p); err == nil && u.IsAbs() {
+		return p
+	}
+	prefix := ""
+	if u, err := url.Parse(g.cfg.BaseURL); err == nil {
+		prefix = strings.TrimSuffix(u.Path, "/")
+	}
+	return prefix + "/" + strings.TrimPrefix(p, "/")
+}
+
+// truncate shortens s to at most n runes, appending an ellipsis when cut.
+// Its argument order suits pipelines: {{.Summary | truncate 80}}.
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n < 0 || len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n])) + "…"
+}
+
+// markdownify renders inline markdown, dropping the paragraph wrapper that a
+// single line would otherwise get.
+func (g *Generator) markdownify(s string) template.HTML {
+	html := strings.TrimSpace(convertMarkdownToHTML(g.md, s))
+	if strings.Count(html, "<p>") == 1 && strings.HasPrefix(html, "<p>") && strings.HasSuffix(html, "</p>") {
+		html = strings.TrimSuffix(strings.TrimPrefix(html, "<p>"), "</p>")
+	}
+	return template.HTML(html)
+}
+
+// slugify lowercases s and joins runs of letters and digits (CJK included)
+// with hyphens.
+func slugify(s string) string {
+	var b strings.Builder
+	pendingDash := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingDash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingDash = false
+			b.WriteRune(r)
+			continue
+		}
+		pendingDash = true
+	}
+	return b.String()
+}
+
+// where keeps the elements of a slice whose field (or niladic method)
+// equals value: {{range where .Posts "Layout" "post"}}.
+func where(list interface{}, field string, value interface{}) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("where: 需要切片, 得到 %T", list)
+	}
+	out := reflect.MakeSlice(v.Type(), 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		fv, err := fieldValue(v.Index(i), field)
+		if err != nil {
+			return nil, fmt.Errorf("where: %w", err)
+		}
+		if reflect.DeepEqual(fv.Interface(), value) || fmt.Sprint(fv.Interface()) == fmt.Sprint(value) {
+			out = reflect.Append(out, v.Index(i))
+		}
+	}
+	return out.Interface(), nil
+}
+
+// sortBy returns a copy of a slice ordered by a field or niladic method,
+// ascending unless order is "desc": {{range sortBy .Posts "Time" "desc"}}.
+func sortBy(list interface{}, field string, order ...string) (interface{}, error) {
+	v := reflect.ValueOf(list)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("sortBy: 需要切片, 得到 %T", list)
+	}
+	out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(out, v)
+	keys := make([]reflect.Value, v.Len())
+	for i := range keys {
+		fv, err := fieldValue(out.Index(i), field)
+		if err != nil {
+			return nil, fmt.Errorf("sortBy: %w", err)
+		}
+		keys[i] = fv
+	}
+	desc := len(order) > 0 && strings.EqualFold(order[0], "desc")
+	idx := make([]int, len(keys))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool {
+		if desc {
+			return lessValue(keys[idx[b]], keys[idx[a]])
+		}
+		return lessValue(keys[idx[a]], keys[idx[b]])
+	})
+	sorted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	for i, j := range idx {
+		sorted.Index(i).Set(out.Index(j))
+	}
+	return sorted.Interface(), nil
+}
+
+func fieldValue(v reflect.Value, name string) (reflect.Value, error) {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() >= 1 {
+		return m.Call(nil)[0], nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if f := v.FieldByName(name); f.IsValid() {
+			return f, nil
+		}
+	case reflect.Map:
+		if f := v.MapIndex(reflect.ValueOf(name)); f.IsValid() {
+			return f, nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("%s 没有字段 %s", v.Type(), name)
+}
+
+func lessValue(a, b reflect.Value) bool {
+	for a.Kind() == reflect.Interface {
+		a = a.Elem()
+	}
+	for b.Kind() == reflect.Interface {
+		b = b.Elem()
+	}
+	if ta, ok := a.Interface().(time.Time); ok {
+		if tb, ok := b.Interface().(time.Time); ok {
+			return ta.Before(tb)
+		}
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+// YearGroup is one year of posts as returned by groupByYear.
+type YearGroup struct {
+	Year  int
+	Posts []Post
+}
+
+// groupByYear buckets posts by publication year, newest year first, keeping
+// the incoming order within a year. Posts with unparseable dates go under 0.
+func groupByYear(posts []Post) []YearGroup {
+	var groups []YearGroup
+	index := make(map[int]int)
+	for _, p := range posts {
+		year := p.Time().Year()
+		if p.Time().IsZero() {
+			year = 0
+		}
+		i, ok := index[year]
+		if !ok {
+			i = len(groups)
+			index[year] = i
+			groups = append(groups, YearGroup{Year: year})
+		}
+		groups[i].Posts = append(groups[i].Posts, p)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Year > groups[j].Year })
+	return groups
+}
+
+func safeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// jsonify encodes v as JSON, typed as template.JS so it can be embedded
+// verbatim in <script> blocks such as JSON-LD.
+func jsonify(v interface{}) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"html/template"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func newTestGenerator(baseURL string) *Generator {
+	cfg := defaultConfig()
+	cfg.BaseURL = baseURL
+	images := newImagePipeline(cfg, fstest.MapFS{}, &diagnostics{})
+	return &Generator{cfg: cfg, md: newMarkdown(cfg, images), images: images, diags: images.diags}
+}
+
+func TestFormatDate(t *testing.T) {
+	tests := []struct {
+		layout string
+		date   interface{}
+		want   string
+	}{
+		{"2006-01-02", "2025-9-2", "2025-09-02"},
+		{"2006-01-02", "2025-09-02", "2025-09-02"},
+		{"zh", "2025-9-2", "2025年9月2日"},
+		{"Mon 2006-01-02", "2025-9-2", "周二 2025-09-02"},
+		{"Monday", "2025-9-7", "星期日"},
+		{"January 2", "2025-9-2", "九月 2"},
+		{"Jan 2", "2025-9-2", "9月 2"},
+		{"01-02", time.Date(2024, 12, 25, 8, 0, 0, 0, time.UTC), "12-25"},
+		{"2006", "not a date", "not a date"},
+		{"2006", 42, "42"},
+	}
+	for _, tt := range tests {
+		if got := formatDate(tt.layout, tt.date); got != tt.want {
+			t.Errorf("formatDate(%q, %v) = %q, want %q", tt.layout, tt.date, got, tt.want)
+		}
+	}
+}
+
+func TestAbsURL(t *testing.T) {
+	tests := []struct {
+		base, path, want string
+	}{
+		{"https://example.com", "/posts/a.html", "https://example.com/posts/a.html"},
+		{"https://example.com/", "posts/a.html", "https://example.com/posts/a.html"},
+		{"https://example.com/blog", "/feed.xml", "https://example.com/blog/feed.xml"},
+		{"https://example.com", "https://other.org/x", "https://other.org/x"},
+	}
+	for _, tt := range tests {
+		if got := newTestGenerator(tt.base).absURL(tt.path); got != tt.want {
+			t.Errorf("absURL(%q) with base %q = %q, want %q", tt.path, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestRelURL(t *testing.T) {
+	tests := []struct {
+		base, path, want string
+	}{
+		{"https://example.com", "/posts/a.html", "/posts/a.html"},
+		{"https://example.com", "posts/a.html", "/posts/a.html"},
+		{"https://example.com/blog/", "/static/style.css", "/blog/static/style.css"},
+		{"https://example.com/blog", "https://other.org/x", "https://other.org/x"},
+	}
+	for _, tt := range tests {
+		if got := newTestGenerator(tt.base).relURL(tt.path); got != tt.want {
+			t.Errorf("relURL(%q) with base %q = %q, want %q", tt.path, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		n    int
+		s    string
+		want string
+	}{
+		{5, "hello", "hello"},
+		{5, "hello world", "hello…"},
+		{6, "hello world", "hello…"},
+		{2, "你好世界", "你好…"},
+		{-1, "unchanged", "unchanged"},
+		{0, "", ""},
+	}
+	for _, tt := range tests {
+		if got := truncate(tt.n, tt.s); got != tt.want {
+			t.Errorf("truncate(%d, %q) = %q, want %q", tt.n, tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownify(t *testing.T) {
+	g := newTestGenerator("https://example.com")
+	tests := []struct {
+		in   string
+		want template.HTML
+	}{
+		{"plain", "plain"},
+		{"**bold** and `code`", "<strong>bold</strong> and <code>code</code>"},
+		{"one\n\ntwo", "<p>one</p>\n<p>two</p>"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := g.markdownify(tt.in); got != tt.want {
+			t.Errorf("markdownify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Hello World", "hello-world"},
+		{"  Go 1.22: what's new?  ", "go-1-22-what-s-new"},
+		{"Redis 缓存设计", "redis-缓存设计"},
+		{"---", ""},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.in); got != tt.want {
+			t.Errorf("slugify(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func testPosts() []Post {
+	return []Post{
+		{Title: "b", Date: "2024-3-1", Layout: "post", Part: 2},
+		{Title: "a", Date: "2025-1-5", Layout: "page", Part: 3},
+		{Title: "c", Date: "2024-11-20", Layout: "post", Part: 1},
+	}
+}
+
+func titles(posts []Post) []string {
+	var out []string
+	for _, p := range posts {
+		out = append(out, p.Title)
+	}
+	return out
+}
+
+func TestWhere(t *testing.T) {
+	tests := []struct {
+		field string
+		value interface{}
+		want  []string
+	}{
+		{"Layout", "post", []string{"b", "c"}},
+		{"Part", 3, []string{"a"}},
+		{"Part", "1", []string{"c"}},
+		{"Layout", "none", nil},
+	}
+	for _, tt := range tests {
+		got, err := where(testPosts(), tt.field, tt.value)
+		if err != nil {
+			t.Fatalf("where(%q, %v): %v", tt.field, tt.value, err)
+		}
+		if titles := titles(got.([]Post)); !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("where(%q, %v) = %v, want %v", tt.field, tt.value, titles, tt.want)
+		}
+	}
+
+	if _, err := where("not a slice", "Title", "x"); err == nil {
+		t.Error("where on a string: want error")
+	}
+	if _, err := where(testPosts(), "Missing", "x"); err == nil {
+		t.Error("where on a missing field: want error")
+	}
+	maps := []map[string]interface{}{{"name": "x"}, {"name": "y"}}
+	got, err := where(maps, "name", "y")
+	if err != nil || len(got.([]map[string]interface{})) != 1 {
+		t.Errorf("where on maps = %v, %v", got, err)
+	}
+}
+
+func TestSortBy(t *testing.T) {
+	tests := []struct {
+		field string
+		order []string
+		want  []string
+	}{
+		{"Title", nil, []string{"a", "b", "c"}},
+		{"Title", []string{"desc"}, []string{"c", "b", "a"}},
+		{"Part", nil, []string{"c", "b", "a"}},
+		{"Time", []string{"DESC"}, []string{"a", "c", "b"}},
+		{"Time", []string{"asc"}, []string{"b", "c", "a"}},
+	}
+	for _, tt := range tests {
+		posts := testPosts()
+		got, err := sortBy(posts, tt.field, tt.order...)
+		if err != nil {
+			t.Fatalf("sortBy(%q, %v): %v", tt.field, tt.order, err)
+		}
+		if titles := titles(got.([]Post)); !reflect.DeepEqual(titles, tt.want) {
+			t.Errorf("sortBy(%q, %v) = %v, want %v", tt.field, tt.order, titles, tt.want)
+		}
+		if titles(posts)[0] != "b" {
+			t.Errorf("sortBy(%q) modified its input", tt.field)
+		}
+	}
+
+	if _, err := sortBy(42, "Title"); err == nil {
+		t.Error("sortBy on an int: want error")
+	}
+}
+
+func TestGroupByYear(t *testing.T) {
+	posts := append(testPosts(), Post{Title: "d", Date: "someday"})
+	got := groupByYear(posts)
+	var years []int
+	var groups [][]string
+	for _, g := range got {
+		years = append(years, g.Year)
+		groups = append(groups, titles(g.Posts))
+	}
+	if want := []int{2025, 2024, 0}; !reflect.DeepEqual(years, want) {
+		t.Errorf("groupByYear years = %v, want %v", years, want)
+	}
+	if want := [][]string{{"a"}, {"b", "c"}, {"d"}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("groupByYear posts = %v, want %v", groups, want)
+	}
+	if got := groupByYear(nil); len(got) != 0 {
+		t.Errorf("groupByYear(nil) = %v, want empty", got)
+	}
+}
+
+func TestSafeHTML(t *testing.T) {
+	tests := []string{"", "<b>x</b>", "a & b"}
+	for _, in := range tests {
+		if got := safeHTML(in); got != template.HTML(in) {
+			t.Errorf("safeHTML(%q) = %q", in, got)
+		}
+	}
+}
+
+func TestJsonify(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want template.JS
+	}{
+		{map[string]interface{}{"b": 1, "a": "x"}, `{"a":"x","b":1}`},
+		{[]string{"</script>"}, `["\u003c/script\u003e"]`},
+		{nil, "null"},
+	}
+	for _, tt := range tests {
+		got, err := jsonify(tt.in)
+		if err != nil {
+			t.Fatalf("jsonify(%v): %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("jsonify(%v) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+	if _, err := jsonify(make(chan int)); err == nil {
+		t.Error("jsonify(chan): want error")
+	}
+}
+
+func TestLangURL(t *testing.T) {
+	site := &Site{Lang: &Language{Code: "en", Prefix: "/en"}}
+	tests := map[string]string{
+		"/links.html":            "/en/links.html",
+		"/":                      "/en/",
+		"https://github.com/x":   "https://github.com/x",
+		"//cdn.example.com/a.js": "//cdn.example.com/a.js",
+		"mailto:me@example.com":  "mailto:me@example.com",
+	}
+	for in, want := range tests {
+		if got := site.LangURL(in); got != want {
+			t.Errorf("LangURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
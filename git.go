@@ -0,0 +1,640 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errNoGitRepo means no .git was found above the site directory.
+var errNoGitRepo = errors.New("不在 git 仓库中")
+
+// gitHash is a SHA-1 object name.
+type gitHash [20]byte
+
+func (h gitHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+func parseGitHash(s string) (gitHash, error) {
+	var h gitHash
+	b, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != len(h) {
+		return h, fmt.Errorf("无效的对象名 %q", s)
+	}
+	copy(h[:], b)
+	return h, nil
+}
+
+// Object types as stored in object headers and packfiles.
+const (
+	gitCommit   = 1
+	gitTree     = 2
+	gitBlob     = 3
+	gitTag      = 4
+	gitOfsDelta = 6
+	gitRefDelta = 7
+)
+
+var gitTypeNames = map[string]int{"commit": gitCommit, "tree": gitTree, "blob": gitBlob, "tag": gitTag}
+
+// gitRepo reads commits and trees straight from a .git directory, loose
+// objects and packfiles alike, so dates can be taken from history without
+// a git binary on the build machine.
+type gitRepo struct {
+	root    string // the work tree
+	gitDir  string // HEAD lives here
+	common  string // objects and refs live here; differs for worktrees
+	packs   []*gitPack
+	shallow map[gitHash]bool
+	trees   map[gitHash][]gitTreeEntry
+}
+
+// openGitRepo finds the repository containing dir.
+func openGitRepo(dir string) (*gitRepo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		dotGit := filepath.Join(abs, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			return newGitRepo(abs, dotGit, info.IsDir())
+		}
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return nil, errNoGitRepo
+		}
+		abs = parent
+	}
+}
+
+func newGitRepo(root, dotGit string, isDir bool) (*gitRepo, error) {
+	r := &gitRepo{root: root, gitDir: dotGit, shallow: make(map[gitHash]bool), trees: make(map[gitHash][]gitTreeEntry)}
+	if !isDir {
+		// A linked worktree or submodule: .git is a "gitdir: <path>" file.
+		data, err := os.ReadFile(dotGit)
+		if err != nil {
+			return nil, err
+		}
+		dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+		if !ok {
+			return nil, fmt.Errorf("无法识别的 %s", dotGit)
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		r.gitDir = dir
+	}
+	r.common = r.gitDir
+	if data, err := os.ReadFile(filepath.Join(r.gitDir, "commondir")); err == nil {
+		common := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(r.gitDir, common)
+		}
+		r.common = common
+	}
+
+	if data, err := os.ReadFile(filepath.Join(r.common, "config")); err == nil {
+		if bytes.Contains(data, []byte("objectformat = sha256")) {
+			return nil, errors.New("不支持 sha256 仓库")
+		}
+	}
+	if data, err := os.ReadFile(filepath.Join(r.common, "shallow")); err == nil {
+		for _, line := range strings.Fields(string(data)) {
+			if h, err := parseGitHash(line); err == nil {
+				r.shallow[h] = true
+			}
+		}
+	}
+	idxs, err := filepath.Glob(filepath.Join(r.common, "objects", "pack", "*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	for _, idx := range idxs {
+		pack, err := openGitPack(r, idx)
+		if err != nil {
+			return nil, err
+		}
+		r.packs = append(r.packs, pack)
+	}
+	return r, nil
+}
+
+// Close releases the open packfiles.
+func (r *gitRepo) Close() {
+	for _, p := range r.packs {
+		p.file.Close()
+	}
+}
+
+// head resolves HEAD to a commit; ok is false on an unborn branch.
+func (r *gitRepo) head() (h gitHash, ok bool, err error) {
+	data, err := os.ReadFile(filepath.Join(r.gitDir, "HEAD"))
+	if err != nil {
+		return h, false, err
+	}
+	ref := strings.TrimSpace(string(data))
+	for i := 0; i < 10; i++ {
+		name, isRef := strings.CutPrefix(ref, "ref: ")
+		if !isRef {
+			h, err := parseGitHash(ref)
+			return h, err == nil, err
+		}
+		target, found, err := r.readRef(name)
+		if err != nil || !found {
+			return h, false, err
+		}
+		ref = target
+	}
+	return h, false, errors.New("HEAD 的符号引用层数过多")
+}
+
+// readRef reads a loose ref, falling back to packed-refs.
+func (r *gitRepo) readRef(name string) (string, bool, error) {
+	for _, dir := range []string{r.gitDir, r.common} {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err == nil {
+			return strings.TrimSpace(string(data)), true, nil
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(r.common, "packed-refs"))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if hash, ref, ok := strings.Cut(line, " "); ok && ref == name {
+			return hash, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// readObject returns an object's type and contents.
+func (r *gitRepo) readObject(h gitHash) (int, []byte, error) {
+	name := h.String()
+	f, err := os.Open(filepath.Join(r.common, "objects", name[:2], name[2:]))
+	if err == nil {
+		defer f.Close()
+		return readLooseObject(f)
+	}
+	for _, p := range r.packs {
+		if off, ok := p.find(h); ok {
+			return p.readAt(off)
+		}
+	}
+	return 0, nil, fmt.Errorf("找不到对象 %s", name)
+}
+
+func readLooseObject(f io.Reader) (int, []byte, error) {
+	zr, err := zlib.NewReader(f)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, err
+	}
+	header, body, ok := bytes.Cut(data, []byte{0})
+	if !ok {
+		return 0, nil, errors.New("对象头损坏")
+	}
+	typeName, _, _ := strings.Cut(string(header), " ")
+	typ, ok := gitTypeNames[typeName]
+	if !ok {
+		return 0, nil, fmt.Errorf("未知的对象类型 %q", typeName)
+	}
+	return typ, body, nil
+}
+
+// gitCommitInfo is the part of a commit the history walk needs.
+type gitCommitInfo struct {
+	tree    gitHash
+	parents []gitHash
+	time    time.Time // committer date
+}
+
+func (r *gitRepo) commit(h gitHash) (gitCommitInfo, error) {
+	var c gitCommitInfo
+	typ, data, err := r.readObject(h)
+	if err != nil {
+		return c, err
+	}
+	if typ != gitCommit {
+		return c, fmt.Errorf("%s 不是提交", h)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			c.tree, err = parseGitHash(value)
+		case "parent":
+			var p gitHash
+			p, err = parseGitHash(value)
+			c.parents = append(c.parents, p)
+		case "committer":
+			c.time, err = parseGitSignatureTime(value)
+		}
+		if err != nil {
+			return c, fmt.Errorf("提交 %s: %w", h, err)
+		}
+	}
+	return c, nil
+}
+
+// parseGitSignatureTime reads the "<unix seconds> <+hhmm>" after the email
+// of an author or committer line.
+func parseGitSignatureTime(sig string) (time.Time, error) {
+	i := strings.LastIndex(sig, ">")
+	if i < 0 {
+		return time.Time{}, fmt.Errorf("无法解析时间 %q", sig)
+	}
+	fields := strings.Fields(sig[i+1:])
+	if len(fields) != 2 || len(fields[1]) != 5 {
+		return time.Time{}, fmt.Errorf("无法解析时间 %q", sig)
+	}
+	secs, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	hours, err1 := strconv.Atoi(fields[1][1:3])
+	mins, err2 := strconv.Atoi(fields[1][3:])
+	if err1 != nil || err2 != nil {
+		return time.Time{}, fmt.Errorf("无法解析时区 %q", fields[1])
+	}
+	offset := hours*3600 + mins*60
+	if fields[1][0] == '-' {
+		offset = -offset
+	}
+	return time.Unix(secs, 0).In(time.FixedZone("", offset)), nil
+}
+
+type gitTreeEntry struct {
+	name string
+	dir  bool
+	hash gitHash
+}
+
+// tree lists a tree's entries; the zero hash is the empty tree.
+func (r *gitRepo) tree(h gitHash) ([]gitTreeEntry, error) {
+	if h == (gitHash{}) {
+		return nil, nil
+	}
+	if entries, ok := r.trees[h]; ok {
+		return entries, nil
+	}
+	typ, data, err := r.readObject(h)
+	if err != nil {
+		return nil, err
+	}
+	if typ != gitTree {
+		return nil, fmt.Errorf("%s 不是目录树", h)
+	}
+	var entries []gitTreeEntry
+	for len(data) > 0 {
+		sp := bytes.IndexByte(data, ' ')
+		nul := bytes.IndexByte(data, 0)
+		if sp < 0 || nul < sp || len(data) < nul+1+len(gitHash{}) {
+			return nil, fmt.Errorf("目录树 %s 已损坏", h)
+		}
+		e := gitTreeEntry{name: string(data[sp+1 : nul]), dir: string(data[:sp]) == "40000"}
+		copy(e.hash[:], data[nul+1:])
+		entries = append(entries, e)
+		data = data[nul+1+len(gitHash{}):]
+	}
+	r.trees[h] = entries
+	return entries, nil
+}
+
+// diffTrees calls changed with the path of every file that differs between
+// trees a and b, descending only into directories descend accepts.
+func (r *gitRepo) diffTrees(a, b gitHash, prefix string, descend func(string) bool, changed func(string)) error {
+	if a == b {
+		return nil
+	}
+	ea, err := r.tree(a)
+	if err != nil {
+		return err
+	}
+	eb, err := r.tree(b)
+	if err != nil {
+		return err
+	}
+	type pair struct{ a, b gitTreeEntry }
+	names := make(map[string]*pair)
+	for _, e := range ea {
+		names[e.name] = &pair{a: e}
+	}
+	for _, e := range eb {
+		if p, ok := names[e.name]; ok {
+			p.b = e
+		} else {
+			names[e.name] = &pair{b: e}
+		}
+	}
+	for name, p := range names {
+		if p.a == p.b {
+			continue
+		}
+		full := path.Join(prefix, name)
+		var subA, subB gitHash
+		var isDir, isFile bool
+		for _, e := range []struct {
+			entry gitTreeEntry
+			sub   *gitHash
+		}{{p.a, &subA}, {p.b, &subB}} {
+			switch {
+			case e.entry.name == "":
+			case e.entry.dir:
+				*e.sub, isDir = e.entry.hash, true
+			default:
+				isFile = true
+			}
+		}
+		if isFile {
+			changed(full)
+		}
+		if isDir && descend(full) {
+			if err := r.diffTrees(subA, subB, full, descend, changed); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lastCommitTimes maps each of files, paths relative to the work tree, to
+// the committer date of the newest first-parent commit that changed it.
+// Files that were never committed are left out.
+func (r *gitRepo) lastCommitTimes(files []string) (map[string]time.Time, error) {
+	want := make(map[string]bool)
+	dirs := make(map[string]bool)
+	for _, f := range files {
+		want[f] = true
+		for d := path.Dir(f); d != "."; d = path.Dir(d) {
+			dirs[d] = true
+		}
+	}
+	descend := func(dir string) bool { return dirs[dir] }
+
+	times := make(map[string]time.Time)
+	h, ok, err := r.head()
+	if err != nil || !ok {
+		return times, err
+	}
+	for len(times) < len(want) {
+		c, err := r.commit(h)
+		if err != nil {
+			return times, err
+		}
+		// A shallow clone's boundary commit is treated as the root.
+		var parentTree gitHash
+		hasParent := len(c.parents) > 0 && !r.shallow[h]
+		if hasParent {
+			parent, err := r.commit(c.parents[0])
+			if err != nil {
+				return times, err
+			}
+			parentTree = parent.tree
+		}
+		err = r.diffTrees(c.tree, parentTree, "", descend, func(f string) {
+			if _, seen := times[f]; want[f] && !seen {
+				times[f] = c.time
+			}
+		})
+		if err != nil {
+			return times, err
+		}
+		if !hasParent {
+			break
+		}
+		h = c.parents[0]
+	}
+	return times, nil
+}
+
+// gitPack is a packfile with its version 2 index.
+type gitPack struct {
+	repo    *gitRepo
+	file    *os.File
+	size    int64
+	fanout  []byte
+	names   []byte
+	offsets []byte
+	large   []byte
+	cache   map[int64]gitPackObject
+}
+
+type gitPackObject struct {
+	typ  int
+	data []byte
+}
+
+func openGitPack(repo *gitRepo, idxPath string) (*gitPack, error) {
+	idx, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) < 8+256*4 || !bytes.HasPrefix(idx, []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(idx[4:]) != 2 {
+		return nil, fmt.Errorf("%s: 只支持第 2 版 pack 索引", idxPath)
+	}
+	fanout := idx[8 : 8+256*4]
+	n := int(binary.BigEndian.Uint32(fanout[255*4:]))
+	names := 8 + 256*4
+	offsets := names + n*(20+4)
+	if len(idx) < offsets+n*4 {
+		return nil, fmt.Errorf("%s: pack 索引已损坏", idxPath)
+	}
+	f, err := os.Open(strings.TrimSuffix(idxPath, ".idx") + ".pack")
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &gitPack{
+		repo:    repo,
+		file:    f,
+		size:    info.Size(),
+		fanout:  fanout,
+		names:   idx[names : names+n*20],
+		offsets: idx[offsets : offsets+n*4],
+		large:   idx[offsets+n*4:],
+		cache:   make(map[int64]gitPackObject),
+	}, nil
+}
+
+// find looks an object up in the index.
+func (p *gitPack) find(h gitHash) (int64, bool) {
+	lo := 0
+	if h[0] > 0 {
+		lo = int(binary.BigEndian.Uint32(p.fanout[(int(h[0])-1)*4:]))
+	}
+	hi := int(binary.BigEndian.Uint32(p.fanout[int(h[0])*4:]))
+	i := lo + sort.Search(hi-lo, func(i int) bool {
+		return bytes.Compare(p.names[(lo+i)*20:(lo+i+1)*20], h[:]) >= 0
+	})
+	if i >= hi || !bytes.Equal(p.names[i*20:(i+1)*20], h[:]) {
+		return 0, false
+	}
+	off := binary.BigEndian.Uint32(p.offsets[i*4:])
+	if off&0x80000000 == 0 {
+		return int64(off), true
+	}
+	j := int(off&0x7fffffff) * 8
+	if j+8 > len(p.large) {
+		return 0, false
+	}
+	return int64(binary.BigEndian.Uint64(p.large[j:])), true
+}
+
+// readAt reads the object at off, resolving deltas against their bases.
+func (p *gitPack) readAt(off int64) (int, []byte, error) {
+	if obj, ok := p.cache[off]; ok {
+		return obj.typ, obj.data, nil
+	}
+	br := bufio.NewReader(io.NewSectionReader(p.file, off, p.size-off))
+	c, err := br.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	typ := int(c>>4) & 7
+	for c&0x80 != 0 { // the inflated size, which zlib tells us anyway
+		if c, err = br.ReadByte(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var baseType int
+	var base []byte
+	switch typ {
+	case gitOfsDelta:
+		c, err := br.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		rel := int64(c & 0x7f)
+		for c&0x80 != 0 {
+			if c, err = br.ReadByte(); err != nil {
+				return 0, nil, err
+			}
+			rel = (rel+1)<<7 | int64(c&0x7f)
+		}
+		if baseType, base, err = p.readAt(off - rel); err != nil {
+			return 0, nil, err
+		}
+	case gitRefDelta:
+		var h gitHash
+		if _, err := io.ReadFull(br, h[:]); err != nil {
+			return 0, nil, err
+		}
+		if baseType, base, err = p.repo.readObject(h); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(br)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return 0, nil, err
+	}
+	if typ == gitOfsDelta || typ == gitRefDelta {
+		if data, err = applyGitDelta(base, data); err != nil {
+			return 0, nil, fmt.Errorf("pack 偏移 %d: %w", off, err)
+		}
+		typ = baseType
+	}
+	if typ == gitCommit || typ == gitTree {
+		p.cache[off] = gitPackObject{typ, data}
+	}
+	return typ, data, nil
+}
+
+// applyGitDelta rebuilds an object from its delta base and the delta's
+// copy and insert instructions.
+func applyGitDelta(base, delta []byte) ([]byte, error) {
+	errCorrupt := errors.New("delta 已损坏")
+	varint := func() (int, bool) {
+		n, shift := 0, 0
+		for len(delta) > 0 {
+			c := delta[0]
+			delta = delta[1:]
+			n |= int(c&0x7f) << shift
+			shift += 7
+			if c&0x80 == 0 {
+				return n, true
+			}
+		}
+		return 0, false
+	}
+	baseSize, ok1 := varint()
+	size, ok2 := varint()
+	if !ok1 || !ok2 || baseSize != len(base) {
+		return nil, errCorrupt
+	}
+	out := make([]byte, 0, size)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		if op&0x80 == 0 {
+			n := int(op)
+			if n == 0 || n > len(delta) {
+				return nil, errCorrupt
+			}
+			out = append(out, delta[:n]...)
+			delta = delta[n:]
+			continue
+		}
+		var offset, n int
+		for i := 0; i < 7; i++ {
+			if op&(1<<i) == 0 {
+				continue
+			}
+			if len(delta) == 0 {
+				return nil, errCorrupt
+			}
+			if i < 4 {
+				offset |= int(delta[0]) << (8 * i)
+			} else {
+				n |= int(delta[0]) << (8 * (i - 4))
+			}
+			delta = delta[1:]
+		}
+		if n == 0 {
+			n = 0x10000
+		}
+		if offset+n > len(base) {
+			return nil, errCorrupt
+		}
+		out = append(out, base[offset:offset+n]...)
+	}
+	if len(out) != size {
+		return nil, errCorrupt
+	}
+	return out, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gitFixture is a throwaway repository made with the git binary.
+type gitFixture struct {
+	t   *testing.T
+	dir string
+}
+
+func newGitFixture(t *testing.T) *gitFixture {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("没有 git")
+	}
+	f := &gitFixture{t: t, dir: t.TempDir()}
+	f.git("", "init", "-q")
+	return f
+}
+
+func (f *gitFixture) git(date string, args ...string) string {
+	f.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = f.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_AUTHOR_NAME=test",
+		"GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=test",
+		"GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_AUTHOR_DATE="+date,
+		"GIT_COMMITTER_DATE="+date,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		f.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return string(out)
+}
+
+// commit writes files and commits them with the given committer date.
+func (f *gitFixture) commit(date string, files map[string]string) {
+	f.t.Helper()
+	for name, content := range files {
+		p := filepath.Join(f.dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			f.t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			f.t.Fatal(err)
+		}
+	}
+	f.git(date, "add", "-A")
+	f.git(date, "commit", "-q", "-m", "commit at "+date)
+}
+
+func TestLastCommitTimes(t *testing.T) {
+	f := newGitFixture(t)
+	// A long file edited in place, so gc stores later versions as deltas.
+	long := strings.Repeat("缓存预热的一行说明, 足够长以便生成 delta。\n", 200)
+	f.commit("2024-01-02T10:00:00+08:00", map[string]string{
+		"content/a.md":       long,
+		"content/notes/b.md": "b",
+		"pages/about.md":     "about",
+	})
+	f.commit("2024-03-04T10:00:00+08:00", map[string]string{
+		"content/notes/b.md": "b edited",
+	})
+	f.commit("2024-05-06T10:00:00+08:00", map[string]string{
+		"content/a.md": long + "新增一行。\n",
+		"README.md":    "not asked for",
+	})
+
+	files := []string{"content/a.md", "content/notes/b.md", "pages/about.md", "content/draft.md"}
+	want := map[string]string{
+		"content/a.md":       "2024-05-06T10:00:00+08:00",
+		"content/notes/b.md": "2024-03-04T10:00:00+08:00",
+		"pages/about.md":     "2024-01-02T10:00:00+08:00",
+	}
+	check := func(kind string) {
+		t.Helper()
+		repo, err := openGitRepo(filepath.Join(f.dir, "content"))
+		if err != nil {
+			t.Fatalf("%s: openGitRepo: %v", kind, err)
+		}
+		defer repo.Close()
+		times, err := repo.lastCommitTimes(files)
+		if err != nil {
+			t.Fatalf("%s: lastCommitTimes: %v", kind, err)
+		}
+		if len(times) != len(want) {
+			t.Errorf("%s: got %d files, want %d: %v", kind, len(times), len(want), times)
+		}
+		for file, date := range want {
+			wantTime, _ := time.Parse(time.RFC3339, date)
+			if got := times[file]; !got.Equal(wantTime) {
+				t.Errorf("%s: %s = %v, want %v", kind, file, got, wantTime)
+			}
+		}
+	}
+
+	check("loose objects")
+
+	f.git("", "gc", "-q", "--aggressive", "--prune=now")
+	loose, _ := filepath.Glob(filepath.Join(f.dir, ".git", "objects", "??", "*"))
+	if len(loose) > 0 {
+		t.Fatalf("gc left %d loose objects", len(loose))
+	}
+	idx, _ := filepath.Glob(filepath.Join(f.dir, ".git", "objects", "pack", "*.idx"))
+	if len(idx) != 1 {
+		t.Fatalf("want one pack, got %v", idx)
+	}
+	if out := f.git("", "verify-pack", "-v", idx[0]); !strings.Contains(out, "chain length = 1") {
+		t.Fatalf("pack has no deltas:\n%s", out)
+	}
+	check("packed objects")
+}
+
+func TestApplyGitDelta(t *testing.T) {
+	base := []byte("hello world")
+	tests := []struct {
+		name  string
+		delta []byte
+		want  string
+	}{
+		// Sizes 11 and 12, copy 6 bytes from offset 0, insert "gopher".
+		{"copy and insert", []byte{11, 12, 0x90, 6, 6, 'g', 'o', 'p', 'h', 'e', 'r'}, "hello gopher"},
+		// Copy 5 bytes from offset 6.
+		{"copy with offset", []byte{11, 5, 0x91, 6, 5}, "world"},
+		{"wrong base size", []byte{10, 5, 0x91, 6, 5}, ""},
+		{"copy past base", []byte{11, 5, 0x91, 8, 5}, ""},
+		{"insert past delta", []byte{11, 5, 5, 'a'}, ""},
+		{"wrong result size", []byte{11, 4, 0x91, 6, 5}, ""},
+	}
+	for _, tt := range tests {
+		got, err := applyGitDelta(base, tt.delta)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%s: want error, got %q", tt.name, got)
+			}
+			continue
+		}
+		if err != nil || !bytes.Equal(got, []byte(tt.want)) {
+			t.Errorf("%s: applyGitDelta = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseGitSignatureTime(t *testing.T) {
+	tests := []struct {
+		sig  string
+		want string
+	}{
+		{"A U Thor <a@example.com> 1700000000 +0800", "2023-11-15T06:13:20+08:00"},
+		{"A U Thor <a@example.com> 1700000000 -0130", "2023-11-14T20:43:20-01:30"},
+		{"A U Thor 1700000000 +0800", ""},
+		{"A U Thor <a@example.com> 1700000000", ""},
+		{"A U Thor <a@example.com> soon +0800", ""},
+		{"A U Thor <a@example.com> 1700000000 +08xx", ""},
+	}
+	for _, tt := range tests {
+		got, err := parseGitSignatureTime(tt.sig)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("parseGitSignatureTime(%q) = %v, want error", tt.sig, got)
+			}
+			continue
+		}
+		if err != nil || got.Format(time.RFC3339) != tt.want {
+			t.Errorf("parseGitSignatureTime(%q) = %v, %v, want %s", tt.sig, got, err, tt.want)
+		}
+	}
+}
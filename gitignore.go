@@ -0,0 +1,206 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	gitignorePath        = ".gitignore"
+	gitignoreBlockBegin  = "# BEGIN blog generator (managed, do not edit)"
+	gitignoreBlockEnd    = "# END blog generator"
+	gitignoreDiffContext = 2
+)
+
+// defaultGitignore seeds a missing .gitignore; the managed block with the
+// build outputs is appended to it.
+const defaultGitignore = `# Binaries
+*.exe
+*.exe~
+*.dll
+*.so
+*.dylib
+
+# Test binary
+*.test
+
+# Output of the go coverage tool
+*.out
+
+# Dependency directories
+vendor/
+
+# Environment variables
+.env
+.env.local
+
+# Editor directories and files
+.vscode/
+.idea/
+*.swp
+*.swo
+*~
+`
+
+// gitignoreBlock lists what the build writes into the working tree.
+func (c Config) gitignoreBlock() string {
+	var b strings.Builder
+	b.WriteString(gitignoreBlockBegin + "\n")
+	for _, dir := range []string{c.PublicDir, c.CacheDir} {
+		b.WriteString(filepath.ToSlash(filepath.Clean(dir)) + "/\n")
+	}
+	staging := filepath.Join(filepath.Dir(filepath.Clean(c.PublicDir)), c.stagingPattern())
+	b.WriteString(filepath.ToSlash(staging) + "/\n")
+	b.WriteString(gitignoreBlockEnd + "\n")
+	return b.String()
+}
+
+// desiredGitignore returns the .gitignore content the configured mode wants
+// given the current content (exists reports whether the file is there).
+// The managed block is only replaced when its BEGIN line is followed by
+// its END line; unbalanced markers are an error rather than a guess that
+// could drop the user's own entries.
+func (c Config) desiredGitignore(current string, exists bool) (string, error) {
+	if !exists {
+		if c.Gitignore == "off" {
+			return current, nil
+		}
+		return defaultGitignore + "\n" + c.gitignoreBlock(), nil
+	}
+	if c.Gitignore != "block" {
+		return current, nil
+	}
+
+	lines := strings.SplitAfter(current, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case gitignoreBlockBegin:
+			if begin >= 0 {
+				return "", fmt.Errorf("%s 第 %d 行: 重复的 %q, 请手动修复", gitignorePath, i+1, gitignoreBlockBegin)
+			}
+			begin = i
+		case gitignoreBlockEnd:
+			if begin < 0 || end >= 0 {
+				return "", fmt.Errorf("%s 第 %d 行: %q 前没有对应的 %q, 请手动修复", gitignorePath, i+1, gitignoreBlockEnd, gitignoreBlockBegin)
+			}
+			end = i
+		}
+	}
+	block := c.gitignoreBlock()
+	if begin >= 0 {
+		if end < 0 {
+			return "", fmt.Errorf("%s 第 %d 行: %q 后缺少 %q, 请手动修复", gitignorePath, begin+1, gitignoreBlockBegin, gitignoreBlockEnd)
+		}
+		return strings.Join(lines[:begin], "") + block + strings.Join(lines[end+1:], ""), nil
+	}
+	if current != "" && !strings.HasSuffix(current, "\n") {
+		current += "\n"
+	}
+	if current != "" {
+		current += "\n"
+	}
+	return current + block, nil
+}
+
+// updateGitignore brings .gitignore in line with Config.Gitignore. With
+// dryRun it only prints a diff of what would change.
+func (g *Generator) updateGitignore(dryRun bool) error {
+	data, err := os.ReadFile(gitignorePath)
+	exists := err == nil
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取 %s: %w", gitignorePath, err)
+	}
+	current := string(data)
+	desired, err := g.cfg.desiredGitignore(current, exists)
+	if err != nil {
+		return err
+	}
+	if desired == current {
+		if dryRun {
+			fmt.Printf("%s 无需修改\n", gitignorePath)
+		}
+		return nil
+	}
+	if dryRun {
+		fmt.Print(lineDiff(gitignorePath, current, desired))
+		return nil
+	}
+	return os.WriteFile(gitignorePath, []byte(desired), 0644)
+}
+
+// gitignoreCommand implements `gitignore [-dry-run]`.
+func gitignoreCommand(cfg Config, args []string) error {
+	fset := flag.NewFlagSet("gitignore", flag.ContinueOnError)
+	dryRun := fset.Bool("dry-run", false, "只显示将要进行的修改")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	return NewGenerator(cfg).updateGitignore(*dryRun)
+}
+
+// lineDiff renders a unified-style diff between two small texts.
+func lineDiff(name, a, b string) string {
+	al := strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	bl := strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	if a == "" {
+		al = nil
+	}
+
+	// Longest common subsequence table, filled from the end.
+	lcs := make([][]int, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			lines = append(lines, diffLine{' ', al[i]})
+			i++
+			j++
+		case j < len(bl) && (i == len(al) || lcs[i][j+1] >= lcs[i+1][j]):
+			lines = append(lines, diffLine{'+', bl[j]})
+			j++
+		default:
+			lines = append(lines, diffLine{'-', al[i]})
+			i++
+		}
+	}
+
+	nearChange := func(k int) bool {
+		for n := max(0, k-gitignoreDiffContext); n <= min(len(lines)-1, k+gitignoreDiffContext); n++ {
+			if lines[n].op != ' ' {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", name, name)
+	for k, l := range lines {
+		if nearChange(k) {
+			fmt.Fprintf(&out, "%c%s\n", l.op, l.text)
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestDesiredGitignore(t *testing.T) {
+	cfg := defaultConfig()
+	block := cfg.gitignoreBlock()
+	begin, end := gitignoreBlockBegin+"\n", gitignoreBlockEnd+"\n"
+
+	tests := []struct {
+		name    string
+		mode    string
+		current string
+		exists  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "missing file", mode: "block", want: defaultGitignore + "\n" + block},
+		{name: "missing file, create", mode: "create", want: defaultGitignore + "\n" + block},
+		{name: "missing file, off", mode: "off", want: ""},
+		{name: "append block", mode: "block", current: "a\nmy-entry", exists: true,
+			want: "a\nmy-entry\n\n" + block},
+		{name: "empty file", mode: "block", current: "", exists: true, want: block},
+		{name: "replace block", mode: "block", exists: true,
+			current: "a\n" + begin + "old/\n" + end + "my-entry\n",
+			want:    "a\n" + block + "my-entry\n"},
+		{name: "block already current", mode: "block", exists: true,
+			current: "a\n\n" + block, want: "a\n\n" + block},
+		{name: "block at the end without newline", mode: "block", exists: true,
+			current: "a\n" + begin + "old/\n" + gitignoreBlockEnd, want: "a\n" + block},
+		{name: "create keeps existing file", mode: "create", exists: true,
+			current: "a\n" + begin + "old/\n", want: "a\n" + begin + "old/\n"},
+		{name: "off keeps existing file", mode: "off", exists: true, current: "a\n", want: "a\n"},
+		{name: "begin without end", mode: "block", exists: true,
+			current: "a\n" + begin + "public/\nmy-entry\nother/\n", wantErr: true},
+		{name: "end without begin", mode: "block", exists: true,
+			current: "a\n" + end + "my-entry\n", wantErr: true},
+		{name: "end before begin", mode: "block", exists: true,
+			current: end + "my-entry\n" + begin, wantErr: true},
+		{name: "two blocks", mode: "block", exists: true,
+			current: begin + end + "my-entry\n" + begin + end, wantErr: true},
+	}
+	for _, tt := range tests {
+		cfg.Gitignore = tt.mode
+		got, err := cfg.desiredGitignore(tt.current, tt.exists)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, got %q", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+		// A second build must leave the file as it is.
+		if again, err := cfg.desiredGitignore(got, true); tt.mode == "block" && (err != nil || again != got) {
+			t.Errorf("%s: second run changed the file to %q, %v", tt.name, again, err)
+		}
+	}
+}
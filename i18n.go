@@ -0,0 +1,181 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// LanguageConfig configures one site language under [languages.<code>].
+// The code is what content files are suffixed with, as in redis.en.md.
+type LanguageConfig struct {
+	Locale string `toml:"locale"` // html lang and hreflang value
+	Title  string `toml:"title"`  // site title, defaulting to Config.SiteTitle
+}
+
+// Language is a site language as templates see it through .Site.Lang.
+// The default language renders at the site root, every other one below
+// /<code>/.
+type Language struct {
+	Code   string
+	Locale string
+	Title  string
+	Prefix string
+
+	strings  map[string]string
+	fallback map[string]string
+}
+
+// T looks key up in the language's UI string table, falling back to the
+// default language and then to the key itself. Arguments are formatted
+// into the string like fmt.Sprintf: {{.Site.Lang.T "archive_total" .Total}}.
+func (l *Language) T(key string, args ...interface{}) string {
+	s, ok := l.strings[key]
+	if !ok {
+		s, ok = l.fallback[key]
+	}
+	if !ok {
+		s = key
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// languageCodes lists the configured languages, the default one first.
+func (c Config) languageCodes() []string {
+	codes := []string{c.DefaultLanguage}
+	for code := range c.Languages {
+		if code != c.DefaultLanguage {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes[1:])
+	return codes
+}
+
+func (c Config) validateLanguages() error {
+	if _, ok := c.Languages[c.DefaultLanguage]; !ok {
+		return fmt.Errorf("default_language %q 不在 languages 中", c.DefaultLanguage)
+	}
+	for code := range c.Languages {
+		if code == "" || strings.ContainsAny(code, `./\ `) {
+			return fmt.Errorf("语言代码无效: %q", code)
+		}
+	}
+	return nil
+}
+
+// splitLang splits a content file name without .md into its base name and
+// language: "redis.en" is redis in en when en is configured, anything else
+// is in the default language.
+func (c Config) splitLang(name string) (base, lang string) {
+	if i := strings.LastIndex(name, "."); i > 0 {
+		if _, ok := c.Languages[name[i+1:]]; ok {
+			return name[:i], name[i+1:]
+		}
+	}
+	return name, c.DefaultLanguage
+}
+
+// bundleIndexes returns the index.md and index.<code>.md files that make dir
+// a page bundle, if any.
+func (c Config) bundleIndexes(dir string) []string {
+	var indexes []string
+	for _, code := range c.languageCodes() {
+		name := bundleIndex
+		if code != c.DefaultLanguage {
+			name = strings.TrimSuffix(bundleIndex, ".md") + "." + code + ".md"
+		}
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			indexes = append(indexes, filepath.Join(dir, name))
+		}
+	}
+	return indexes
+}
+
+// loadLanguages builds every configured language with its UI strings from
+// i18n/<code>.toml in the layered i18n directories.
+func (g *Generator) loadLanguages() ([]*Language, error) {
+	tables := make(map[string]map[string]string)
+	for _, code := range g.cfg.languageCodes() {
+		table := make(map[string]string)
+		data, err := fs.ReadFile(g.i18nFS, code+".toml")
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if _, err := toml.Decode(string(data), &table); err != nil {
+				return nil, fmt.Errorf("解析 i18n/%s.toml: %w", code, err)
+			}
+		}
+		tables[code] = table
+	}
+
+	var languages []*Language
+	for _, code := range g.cfg.languageCodes() {
+		lc := g.cfg.Languages[code]
+		lang := &Language{
+			Code:     code,
+			Locale:   lc.Locale,
+			Title:    lc.Title,
+			strings:  tables[code],
+			fallback: tables[g.cfg.DefaultLanguage],
+		}
+		if lang.Locale == "" {
+			lang.Locale = code
+		}
+		if lang.Title == "" {
+			lang.Title = g.cfg.SiteTitle
+		}
+		if code != g.cfg.DefaultLanguage {
+			lang.Prefix = "/" + code
+		}
+		languages = append(languages, lang)
+	}
+	return languages, nil
+}
+
+// Translation points at the same entry in another language.
+type Translation struct {
+	Lang   string
+	Locale string
+	Title  string
+	URL    string
+}
+
+// linkTranslations fills in Translations for entries that share a kind,
+// section and base file name across languages.
+func linkTranslations(entries []Post, languages []*Language) {
+	locales := make(map[string]string)
+	for _, l := range languages {
+		locales[l.Code] = l.Locale
+	}
+	groups := make(map[string][]int)
+	for i, p := range entries {
+		groups[p.translationKey] = append(groups[p.translationKey], i)
+	}
+	for _, group := range groups {
+		for _, i := range group {
+			for _, j := range group {
+				if i == j {
+					continue
+				}
+				other := entries[j]
+				entries[i].Translations = append(entries[i].Translations, Translation{
+					Lang:   other.Lang,
+					Locale: locales[other.Lang],
+					Title:  other.Title,
+					URL:    other.URL(),
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"golang.org/x/image/draw"
+)
+
+// imageSizes is the sizes attribute emitted with every srcset; it matches
+// the 800px content column in style.css.
+const imageSizes = "(max-width: 800px) 100vw, 800px"
+
+// imageVariant is one rendition of a source image.
+type imageVariant struct {
+	URL   string
+	Width int
+}
+
+// processedImage describes a local image and every rendition generated for it.
+type processedImage struct {
+	Width    int
+	Height   int
+	Variants []imageVariant // original format, ascending width
+	WebP     []imageVariant // ascending width
+}
+
+// imagePipeline resizes local images referenced from markdown into the
+// configured widths plus WebP, keeping encoded variants in a cache directory
+// keyed by source content so unchanged images are not re-encoded.
+type imagePipeline struct {
+	static   fs.FS
+	cacheDir string
+	widths   []int
+	diags    *diagnostics
+
+	images  map[string]*processedImage
+	outputs map[string]string // public-relative path -> cached file
+	sources map[string]string // bundle resource URL -> source file
+}
+
+func newImagePipeline(cfg Config, static fs.FS, diags *diagnostics) *imagePipeline {
+	widths := append([]int(nil), cfg.ImageWidths...)
+	sort.Ints(widths)
+	return &imagePipeline{
+		static:   static,
+		cacheDir: filepath.Join(cfg.CacheDir, "images"),
+		widths:   widths,
+		diags:    diags,
+		images:   make(map[string]*processedImage),
+		outputs:  make(map[string]string),
+		sources:  make(map[string]string),
+	}
+}
+
+// AddSource makes an image outside StaticDir, such as a page bundle
+// resource, eligible for processing under the given URL.
+func (p *imagePipeline) AddSource(url, file string) {
+	p.sources[url] = file
+}
+
+// readSource loads a /static/... or bundle resource image. It reports false
+// for remote images and formats the pipeline does not handle.
+func (p *imagePipeline) readSource(src string) ([]byte, bool, error) {
+	switch strings.ToLower(path.Ext(src)) {
+	case ".png", ".jpg", ".jpeg":
+	default:
+		return nil, false, nil
+	}
+	if file, ok := p.sources[src]; ok {
+		data, err := os.ReadFile(file)
+		return data, true, err
+	}
+	rel, ok := strings.CutPrefix(src, "/static/")
+	if !ok {
+		return nil, false, nil
+	}
+	data, err := fs.ReadFile(p.static, rel)
+	return data, true, err
+}
+
+// Process returns the renditions of src, generating missing ones on first use.
+func (p *imagePipeline) Process(src string) (*processedImage, error) {
+	if img, ok := p.images[src]; ok {
+		return img, nil
+	}
+	file := src
+	if unescaped, err := url.PathUnescape(src); err == nil {
+		file = unescaped
+	}
+	data, ok, err := p.readSource(file)
+	if !ok || err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("读取图片尺寸 %s: %w", src, err)
+	}
+
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:8])
+	ext := path.Ext(file)
+	stem := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(file, "/"), "static/"), ext)
+
+	img := &processedImage{
+		Width:  cfg.Width,
+		Height: cfg.Height,
+	}
+	var (
+		decoded   image.Image
+		decodeErr error
+	)
+	decode := func() (image.Image, error) {
+		if decoded == nil && decodeErr == nil {
+			decoded, _, decodeErr = image.Decode(bytes.NewReader(data))
+		}
+		return decoded, decodeErr
+	}
+
+	for _, w := range append(p.targetWidths(cfg.Width), cfg.Width) {
+		if w == cfg.Width {
+			img.Variants = append(img.Variants, imageVariant{URL: src, Width: w})
+		} else {
+			v, err := p.variant(stem, key, ext, w, cfg, decode)
+			if err != nil {
+				return nil, err
+			}
+			img.Variants = append(img.Variants, v)
+		}
+		v, err := p.variant(stem, key, ".webp", w, cfg, decode)
+		if err != nil {
+			return nil, err
+		}
+		img.WebP = append(img.WebP, v)
+	}
+
+	p.images[src] = img
+	return img, nil
+}
+
+// targetWidths lists the configured widths narrower than the source.
+func (p *imagePipeline) targetWidths(srcWidth int) []int {
+	var out []int
+	for _, w := range p.widths {
+		if w < srcWidth {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func (p *imagePipeline) variant(stem, key, ext string, width int, cfg image.Config, decode func() (image.Image, error)) (imageVariant, error) {
+	cached := filepath.Join(p.cacheDir, fmt.Sprintf("%s-%d%s", key, width, ext))
+	if _, err := os.Stat(cached); err != nil {
+		src, err := decode()
+		if err != nil {
+			return imageVariant{}, fmt.Errorf("解码图片 %s: %w", stem, err)
+		}
+		if err := encodeResized(cached, src, width, width*cfg.Height/cfg.Width, ext); err != nil {
+			return imageVariant{}, fmt.Errorf("生成图片 %s: %w", cached, err)
+		}
+	}
+	rel := path.Join("static", "processed", fmt.Sprintf("%s-%d%s", stem, width, ext))
+	p.outputs[rel] = cached
+	return imageVariant{URL: "/" + rel, Width: width}, nil
+}
+
+func encodeResized(dst string, src image.Image, width, height int, ext string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out := src
+	if b := src.Bounds(); b.Dx() != width {
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(rgba, rgba.Bounds(), src, b, draw.Over, nil)
+		out = rgba
+	}
+
+	// Write to a temporary name first so an interrupted build never leaves a
+	// truncated file in the cache.
+	tmp := dst + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(ext) {
+	case ".webp":
+		err = nativewebp.Encode(f, out, nil)
+	case ".png":
+		err = png.Encode(f, out)
+	default:
+		err = jpeg.Encode(f, out, &jpeg.Options{Quality: 85})
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// Publish copies every variant referenced during the build into the public tree.
+func (p *imagePipeline) Publish(publicDir string) error {
+	for rel, cached := range p.outputs {
+		data, err := os.ReadFile(cached)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(publicDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// srcset lists the variants as srcset candidates. Their URLs are escaped
+// segment by segment since spaces and commas separate candidates.
+func srcset(variants []imageVariant) string {
+	parts := make([]string, len(variants))
+	for i, v := range variants {
+		parts[i] = fmt.Sprintf("%s %dw", escapeURLPath(v.URL), v.Width)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// escapeURLPath percent-encodes each segment of a site path, leaving
+// segments that are already encoded as they are.
+func escapeURLPath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		if unescaped, err := url.PathUnescape(seg); err == nil {
+			seg = unescaped
+		}
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+// imageSrc is src as goldmark's own image renderer writes it: dangerous
+// URLs such as javascript: are dropped and the rest is percent-encoded,
+// local paths segment by segment like srcset.
+func imageSrc(src string) string {
+	if gmhtml.IsDangerousURL([]byte(src)) {
+		return ""
+	}
+	if u, err := url.Parse(src); err == nil && u.Scheme == "" && u.Host == "" && u.RawQuery == "" && u.Fragment == "" {
+		return escapeURLPath(src)
+	}
+	return string(util.URLEscape([]byte(src), true))
+}
+
+// sourceFileKey holds the content file being converted, so problems found
+// while rendering can be reported against it.
+var sourceFileKey = parser.NewContextKey()
+
+// imageSourceAttr carries sourceFileKey from the parser to imageRenderer on
+// each image node; the renderer writes its own attributes, so it never
+// reaches the output.
+const imageSourceAttr = "source-file"
+
+// imageSourceTransformer tags images with the file they were written in.
+type imageSourceTransformer struct{}
+
+func (imageSourceTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	file, _ := pc.Get(sourceFileKey).(string)
+	if file == "" {
+		return
+	}
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			img.SetAttributeString(imageSourceAttr, file)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// imageRenderer renders markdown images, turning local ones into a
+// <picture> with WebP and resized sources. Remote images and images the
+// pipeline cannot read fall back to a plain lazy-loaded <img>.
+type imageRenderer struct {
+	images *imagePipeline
+}
+
+func (r *imageRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindImage, r.renderImage)
+}
+
+func (r *imageRenderer) renderImage(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*ast.Image)
+	src := string(n.Destination)
+	alt := html.EscapeString(nodeText(n, source))
+	title := ""
+	if len(n.Title) > 0 {
+		title = fmt.Sprintf(` title="%s"`, html.EscapeString(string(n.Title)))
+	}
+
+	img, err := r.images.Process(src)
+	if err != nil {
+		file := src
+		if v, ok := n.AttributeString(imageSourceAttr); ok {
+			file, _ = v.(string)
+		}
+		r.images.diags.Warnf(file, 0, "处理图片 %s: %v", src, err)
+	}
+	if img == nil {
+		fmt.Fprintf(w, `<img src="%s" alt="%s"%s loading="lazy">`, html.EscapeString(imageSrc(src)), alt, title)
+		return ast.WalkSkipChildren, nil
+	}
+
+	fmt.Fprintf(w, `<picture><source type="image/webp" srcset="%s" sizes="%s">`, html.EscapeString(srcset(img.WebP)), imageSizes)
+	fmt.Fprintf(w, `<img src="%s" srcset="%s" sizes="%s" width="%d" height="%d" alt="%s"%s loading="lazy" decoding="async"></picture>`,
+		html.EscapeString(imageSrc(src)), html.EscapeString(srcset(img.Variants)), imageSizes, img.Width, img.Height, alt, title)
+	return ast.WalkSkipChildren, nil
+}
+
+// nodeText concatenates the plain text under n, used for alt attributes.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestImageSrc(t *testing.T) {
+	tests := []struct {
+		src, want string
+	}{
+		{"/static/pic.png", "/static/pic.png"},
+		{"/static/my pic.png", "/static/my%20pic.png"},
+		{"/static/my%20pic.png", "/static/my%20pic.png"},
+		{"/static/a,b.png", "/static/a%2Cb.png"},
+		{"图片/猫.png", "%E5%9B%BE%E7%89%87/%E7%8C%AB.png"},
+		{"https://example.com/a b.png?w=1", "https://example.com/a%20b.png?w=1"},
+		{"javascript:alert(1)", ""},
+		{"data:text/html,<b>x</b>", ""},
+	}
+	for _, tt := range tests {
+		if got := imageSrc(tt.src); got != tt.want {
+			t.Errorf("imageSrc(%q) = %q, want %q", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestSrcset(t *testing.T) {
+	variants := []imageVariant{
+		{URL: "/static/img/my pic-480w.webp", Width: 480},
+		{URL: "/static/img/a,b-800w.webp", Width: 800},
+	}
+	want := "/static/img/my%20pic-480w.webp 480w, /static/img/a%2Cb-800w.webp 800w"
+	if got := srcset(variants); got != want {
+		t.Errorf("srcset = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import "time"
+
+// entryJSONLD describes a post as a schema.org BlogPosting, or a page as a
+// WebPage, for the JSON-LD block templates embed with jsonify.
+func (g *Generator) entryJSONLD(site *Site, post Post) map[string]interface{} {
+	ld := map[string]interface{}{
+		"@context":         "https://schema.org",
+		"@type":            "WebPage",
+		"headline":         post.Title,
+		"url":              g.absURL(post.URL()),
+		"inLanguage":       site.Lang.Locale,
+		"mainEntityOfPage": g.absURL(post.URL()),
+	}
+	if post.Kind == kindPost {
+		ld["@type"] = "BlogPosting"
+	}
+	if t := post.Time(); !t.IsZero() {
+		ld["datePublished"] = t.Format(time.RFC3339)
+	}
+	if !post.Lastmod.IsZero() {
+		ld["dateModified"] = post.Lastmod.Format(time.RFC3339)
+	}
+
+	var authors []map[string]interface{}
+	for _, a := range site.authorsOf(post) {
+		person := map[string]interface{}{
+			"@type": "Person",
+			"name":  a.Name,
+			"url":   g.absURL(a.URL()),
+		}
+		if a.Avatar != "" {
+			person["image"] = a.Avatar
+		}
+		if home := a.Homepage(); home != "" {
+			person["sameAs"] = []string{home}
+		}
+		authors = append(authors, person)
+	}
+	if len(authors) > 0 {
+		ld["author"] = authors
+	}
+	return ld
+}
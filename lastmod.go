@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+)
+
+// applyLastmod fills in Lastmod for entries without a lastmod front matter
+// field: the date of the last commit touching their file when GitLastmod is
+// on and the site is in a git repository, else their date. Uncommitted
+// files fall back to their date too.
+func (g *Generator) applyLastmod(entries []Post) {
+	var times map[string]time.Time
+	var rel map[string]string
+	if g.cfg.GitLastmod {
+		times, rel = g.gitLastmod(entries)
+	}
+	for i := range entries {
+		p := &entries[i]
+		if !p.Lastmod.IsZero() {
+			continue
+		}
+		if t, ok := times[rel[p.File]]; ok {
+			p.Lastmod = t
+		} else {
+			p.Lastmod = p.Time()
+		}
+	}
+}
+
+// gitLastmod looks up the last commit dates of the entries' files, keyed by
+// their path relative to the repository root, which rel maps File to.
+// Failing to read the history is a warning, not a build error.
+func (g *Generator) gitLastmod(entries []Post) (times map[string]time.Time, rel map[string]string) {
+	repo, err := openGitRepo(".")
+	if errors.Is(err, errNoGitRepo) {
+		return nil, nil
+	}
+	if err != nil {
+		g.diags.Warnf(".git", 0, "读取 git 历史: %v", err)
+		return nil, nil
+	}
+	defer repo.Close()
+
+	rel = make(map[string]string)
+	var files []string
+	for _, p := range entries {
+		abs, err := filepath.Abs(p.File)
+		if err != nil {
+			continue
+		}
+		r, err := filepath.Rel(repo.root, abs)
+		if err != nil {
+			continue
+		}
+		rel[p.File] = filepath.ToSlash(r)
+		files = append(files, rel[p.File])
+	}
+	times, err = repo.lastCommitTimes(files)
+	if err != nil {
+		g.diags.Warnf(repo.gitDir, 0, "读取 git 历史: %v", err)
+	}
+	return times, rel
+}
+
+// newestLastmod is the latest Lastmod among posts, zero if there are none.
+func newestLastmod(posts []Post) time.Time {
+	var newest time.Time
+	for _, p := range posts {
+		if p.Lastmod.After(newest) {
+			newest = p.Lastmod
+		}
+	}
+	return newest
+}
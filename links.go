@@ -0,0 +1,199 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Link represents a friend link. Links sharing a Category are shown as one
+// group, in the order the category first appears in the links file.
+type Link struct {
+	Name     string   `toml:"name"`
+	URL      string   `toml:"url"`
+	Desc     string   `toml:"desc"`
+	Category string   `toml:"category"`
+	Avatar   string   `toml:"avatar"`
+	Tags     []string `toml:"tags"`
+
+	// Line is where the link's [[links]] table starts in the links file.
+	Line int `toml:"-"`
+}
+
+// LinkGroup is one category of the friend links page; Name is empty for
+// links without a category.
+type LinkGroup struct {
+	Name  string
+	Links []Link
+}
+
+// loadLinks reads the friend links file, recording the line of each entry
+// so diagnostics can point at it.
+func loadLinks(path string) ([]Link, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var linksConfig struct {
+		Links []Link `toml:"links"`
+	}
+	if _, err := toml.Decode(string(data), &linksConfig); err != nil {
+		return nil, fmt.Errorf("解析 %s: %w", path, err)
+	}
+
+	lines := tableLines(data, "links")
+	links := linksConfig.Links
+	if len(lines) == len(links) {
+		for i := range links {
+			links[i].Line = lines[i]
+		}
+	}
+	return links, nil
+}
+
+// validateLinks reports entries without a name, with a URL that is not an
+// absolute http(s) URL or an unusable avatar as errors, and repeated URLs as
+// warnings.
+func (g *Generator) validateLinks(links []Link) {
+	file := g.cfg.LinksFile
+	seen := make(map[string]Link)
+	for _, link := range links {
+		if strings.TrimSpace(link.Name) == "" {
+			g.diags.Errorf(file, link.Line, "友链缺少名称 (name)")
+		}
+		key, err := linkKey(link.URL)
+		if err != nil {
+			g.diags.Errorf(file, link.Line, "友链 %s 的地址无效: %v", link.Name, err)
+			continue
+		}
+		if link.Avatar != "" {
+			if !validAssetURL(link.Avatar) {
+				g.diags.Errorf(file, link.Line, "友链 %s 的头像地址无效: %q", link.Name, link.Avatar)
+			}
+		}
+		if prev, ok := seen[key]; ok {
+			g.diags.Warnf(file, link.Line, "友链 %s 与第 %d 行的 %s 地址重复", link.Name, prev.Line, prev.Name)
+			continue
+		}
+		seen[key] = link
+	}
+}
+
+// linkKey validates a friend link URL and normalizes it for duplicate
+// detection: case-insensitive host, no trailing slash, no fragment.
+func linkKey(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", fmt.Errorf("缺少 url")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", fmt.Errorf("需要 http(s) 绝对地址: %q", raw)
+	}
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.Host + u.Path + "?" + u.RawQuery, nil
+}
+
+// groupLinks buckets links by Category, keeping file order.
+func groupLinks(links []Link) []LinkGroup {
+	var groups []LinkGroup
+	index := make(map[string]int)
+	for _, link := range links {
+		i, ok := index[link.Category]
+		if !ok {
+			i = len(groups)
+			index[link.Category] = i
+			groups = append(groups, LinkGroup{Name: link.Category})
+		}
+		groups[i].Links = append(groups[i].Links, link)
+	}
+	return groups
+}
+
+// renderLinks writes the friend links page of one language from the links
+// Run loaded.
+func (g *Generator) renderLinks(site *Site) error {
+	tmpl, err := g.templates.Page("links")
+	if err != nil {
+		return err
+	}
+
+	outPath := filepath.Join(g.siteDir(site), "links.html")
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("创建 %s: %w", outPath, err)
+	}
+	defer f.Close()
+
+	ctx := map[string]interface{}{
+		"Site":   site,
+		"Title":  site.Lang.T("links"),
+		"Links":  g.links,
+		"Groups": groupLinks(g.links),
+	}
+	if err := tmpl.Execute(f, ctx); err != nil {
+		return fmt.Errorf("渲染友链页: %w", err)
+	}
+	return nil
+}
+
+// linksCommand implements `links check [-timeout d]`, probing every friend
+// link and failing when any of them looks dead.
+func linksCommand(cfg Config, args []string) error {
+	if len(args) == 0 || args[0] != "check" {
+		return fmt.Errorf("用法: links check [-timeout d]")
+	}
+	fset := flag.NewFlagSet("links check", flag.ContinueOnError)
+	timeout := fset.Duration("timeout", cfg.CheckTimeout, "单个链接的请求超时")
+	if err := fset.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	links, err := loadLinks(cfg.LinksFile)
+	if err != nil {
+		return fmt.Errorf("读取友链: %w", err)
+	}
+	client, err := newProbeClient(cfg.CheckProxy, *timeout)
+	if err != nil {
+		return err
+	}
+
+	problems, checked := checkLinks(&linkChecker{client: client}, cfg.LinksFile, links)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("发现 %d 个失效友链", len(problems))
+	}
+	fmt.Printf("友链检查通过 (%d 个)\n", checked)
+	return nil
+}
+
+// checkLinks probes the friend links from file, returning the problems in
+// file order and how many distinct URLs were probed.
+func checkLinks(c *linkChecker, file string, links []Link) ([]linkProblem, int) {
+	var problems []linkProblem
+	targets := make(map[string][]linkProblem)
+	for _, link := range links {
+		loc := linkProblem{File: file, Line: link.Line, Ref: link.URL}
+		if _, err := linkKey(link.URL); err != nil {
+			loc.Reason = err.Error()
+			problems = append(problems, loc)
+			continue
+		}
+		targets[link.URL] = append(targets[link.URL], loc)
+	}
+	problems = append(problems, c.checkExternal(targets)...)
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Line < problems[j].Line })
+	return problems, len(targets)
+}
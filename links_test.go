@@ -0,0 +1,146 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckLinks(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/live", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/head-not-allowed", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/gone", http.NotFound)
+	live := httptest.NewServer(mux)
+	defer live.Close()
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	unreachable := down.URL + "/anything"
+	down.Close()
+
+	links := []Link{
+		{Name: "live", URL: live.URL + "/live", Line: 1},
+		{Name: "get only", URL: live.URL + "/head-not-allowed", Line: 5},
+		{Name: "404", URL: live.URL + "/gone", Line: 9},
+		{Name: "unreachable", URL: unreachable, Line: 13},
+		{Name: "invalid", URL: "ftp://example.com", Line: 17},
+		{Name: "live again", URL: live.URL + "/live", Line: 21},
+	}
+	c := &linkChecker{client: &http.Client{Timeout: 5 * time.Second}}
+	problems, checked := checkLinks(c, "links.toml", links)
+
+	if checked != 4 {
+		t.Errorf("checked %d URLs, want 4", checked)
+	}
+	var lines []int
+	for _, p := range problems {
+		lines = append(lines, p.Line)
+		if p.File != "links.toml" || p.Reason == "" {
+			t.Errorf("problem %+v: want File links.toml and a reason", p)
+		}
+	}
+	if want := []int{9, 13, 17}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("problems on lines %v, want %v: %v", lines, want, problems)
+	}
+	if len(problems) == 3 && !strings.Contains(problems[0].Reason, "404") {
+		t.Errorf("404 reason = %q", problems[0].Reason)
+	}
+}
+
+func TestLoadLinksLines(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "links.toml")
+	data := `# friends
+
+[[links]]
+name = "a"
+url = "https://a.example.com"
+
+[[links]]
+name = "b"
+url = "https://b.example.com"
+`
+	if err := os.WriteFile(file, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	links, err := loadLinks(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 2 || links[0].Line != 3 || links[1].Line != 7 {
+		t.Errorf("loadLinks = %+v, want lines 3 and 7", links)
+	}
+}
+
+func TestValidateLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		links []Link
+		want  []Diagnostic
+	}{
+		{
+			name: "valid",
+			links: []Link{
+				{Name: "a", URL: "https://a.example.com", Avatar: "https://a.example.com/me.png", Line: 1},
+				{Name: "b", URL: "https://b.example.com/blog", Avatar: "/static/b.png", Line: 5},
+			},
+		},
+		{
+			name: "duplicates ignore case, trailing slash and fragment",
+			links: []Link{
+				{Name: "a", URL: "https://A.example.com/blog/", Line: 1},
+				{Name: "a2", URL: "https://a.example.com/blog#top", Line: 5},
+			},
+			want: []Diagnostic{{Severity: SeverityWarning, Line: 5}},
+		},
+		{
+			name: "different query is not a duplicate",
+			links: []Link{
+				{Name: "a", URL: "https://a.example.com/?id=1", Line: 1},
+				{Name: "b", URL: "https://a.example.com/?id=2", Line: 5},
+			},
+		},
+		{
+			name: "invalid",
+			links: []Link{
+				{Name: "", URL: "https://a.example.com", Line: 1},
+				{Name: "relative", URL: "/links.html", Line: 5},
+				{Name: "scheme", URL: "mailto:me@example.com", Line: 9},
+				{Name: "missing", URL: "", Line: 13},
+				{Name: "avatar", URL: "https://c.example.com", Avatar: "javascript:alert(1)", Line: 17},
+			},
+			want: []Diagnostic{
+				{Severity: SeverityError, Line: 1},
+				{Severity: SeverityError, Line: 5},
+				{Severity: SeverityError, Line: 9},
+				{Severity: SeverityError, Line: 13},
+				{Severity: SeverityError, Line: 17},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGenerator("https://example.com")
+			g.cfg.LinksFile = "links.toml"
+			g.validateLinks(tt.links)
+			var got []Diagnostic
+			for _, d := range g.diags.items {
+				if d.File != "links.toml" || d.Message == "" {
+					t.Errorf("diagnostic %+v: want File links.toml and a message", d)
+				}
+				got = append(got, Diagnostic{Severity: d.Severity, Line: d.Line})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diagnostics = %+v, want %+v", g.diags.items, tt.want)
+			}
+		})
+	}
+}
@@ -5,11 +5,12 @@ import (
 	"bytes"
 	"fmt"
 	"html/template"
+	"io/fs"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
-	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,9 +18,11 @@ import (
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
 	"github.com/alecthomas/chroma/v2/styles"
 	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
-	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
 )
 
 // Post represents a blog post
@@ -29,337 +32,630 @@ type Post struct {
 	Content template.HTML
 	Slug    string
 	Summary string
+
+	// BundleDir is set for page bundles (content/<slug>/index.md); Resources
+	// lists its sibling files, which are published next to the post.
+	BundleDir string
+	Resources []string
+
+	// Backlinks lists the posts that reference this one.
+	Backlinks []PostRef
+
+	// Layout names the template fragment the post renders with, from the
+	// layout front matter field; it defaults to "post", or for sections
+	// other than postsSection to "<section>/post" when that template exists.
+	Layout         string
+	explicitLayout bool
+
+	// File is the markdown source the post was loaded from.
+	File string
+
+	// Kind is kindPost for ContentDir entries and kindPage for standalone
+	// pages from PagesDir, which render to top-level URLs.
+	Kind string
+
+	// Section is the ContentDir subdirectory a post belongs to, and the
+	// prefix of its URL; posts directly in ContentDir are in postsSection.
+	// Pages have no section.
+	Section string
+
+	// Menu, when set, adds the page to the site navigation under that
+	// label; Weight orders menu entries, lowest first.
+	Menu   string
+	Weight int
+
+	// Series names the series the post is part of; Part orders the posts
+	// of a series, ties broken by date.
+	Series string
+	Part   int
+
+	// Aliases are old site paths that redirect to the post.
+	Aliases []string
+
+	// Authors lists the IDs of the authors the post is credited to.
+	Authors []string
+
+	// Lang is the language code from the file name (redis.en.md), else the
+	// default language; Translations lists the same entry in the others.
+	Lang         string
+	Translations []Translation
+
+	// Lastmod is when the entry last changed: the lastmod front matter
+	// field, else the last commit touching its file, else its date.
+	Lastmod time.Time
+
+	translationKey string // kind, section and base file name
+	url            string // expanded permalink
+	body           string // markdown after the front matter
+	bodyLine       int    // file line body starts on
+	aliasesLine    int    // file line of the aliases field
+}
+
+// Time is the parsed front matter date, zero if it does not parse.
+func (p Post) Time() time.Time {
+	t, _ := parsePostDate(p.Date)
+	return t
+}
+
+// Updated reports whether the post changed on a later day than its date.
+func (p Post) Updated() bool {
+	date := p.Time()
+	return !date.IsZero() && p.Lastmod.Format("2006-01-02") > date.Format("2006-01-02")
+}
+
+// URL is the site path the post is rendered to, from its permalink pattern.
+func (p Post) URL() string {
+	return p.url
+}
+
+// ResourceURL is the site directory a bundle's resources are published to.
+func (p Post) ResourceURL() string {
+	return resourceDir(p.url)
 }
 
-// Link represents a friend link
-type Link struct {
-	Name string `toml:"name"`
-	URL  string `toml:"url"`
-	Desc string `toml:"desc"`
+// RefKey is how cross-references address the post: its slug, prefixed with
+// the section for sections other than postsSection.
+func (p Post) RefKey() string {
+	if p.Kind == kindPage || p.Section == postsSection {
+		return p.Slug
+	}
+	return p.Section + "/" + p.Slug
 }
 
-// Site represents the whole blog site
+// Site represents the blog in one language; a multilingual build renders
+// one Site per language.
 type Site struct {
 	Title   string
 	BaseURL string
 	Posts   []Post
+	Pages   []Post
+	Menu    []MenuEntry
+
+	// Sections lists every section, postsSection (whose posts are also
+	// Posts) first.
+	Sections []Section
+
+	// Series lists every series named in post front matter.
+	Series []Series
+
+	// Authors lists the authors with posts on this site; allAuthors is the
+	// whole authors file, see Site.Author.
+	Authors    []Author
+	allAuthors []Author
+
+	// Data holds the files of DataDir, see loadData.
+	Data map[string]interface{}
+
+	// Lang is the language of this site; Languages lists all of them.
+	Lang      *Language
+	Languages []*Language
+}
+
+// LangURL prefixes a root-relative site path with the language directory:
+// {{.Site.LangURL "/archive.html"}}. Other URLs, such as external links from
+// data files, are returned as they are.
+func (s *Site) LangURL(p string) string {
+	if !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	return s.Lang.Prefix + p
+}
+
+// homeTranslations links the language homes other than the site's own.
+func (s *Site) homeTranslations() []Translation {
+	var translations []Translation
+	for _, l := range s.Languages {
+		if l != s.Lang {
+			translations = append(translations, Translation{Lang: l.Code, Locale: l.Locale, Title: l.Title, URL: l.Prefix + "/"})
+		}
+	}
+	return translations
 }
 
 // Config holds all paths and site metadata so nothing scatters magic strings.
 type Config struct {
-	SiteTitle    string
-	BaseURL      string
-	ContentDir   string
-	TemplatesDir string
-	PublicDir    string
-	StaticDir    string
-}
+	SiteTitle    string `toml:"title"`
+	BaseURL      string `toml:"base_url"`
+	ContentDir   string `toml:"content_dir"`
+	TemplatesDir string `toml:"templates_dir"`
+	PublicDir    string `toml:"public_dir"`
+	StaticDir    string `toml:"static_dir"`
+	PagesDir     string `toml:"pages_dir"`
+	SeriesDir    string `toml:"series_dir"`
+	DataDir      string `toml:"data_dir"`
+	I18nDir      string `toml:"i18n_dir"`
+
+	// Theme selects ThemesDir/<Theme>/{templates,static}; files in
+	// TemplatesDir and StaticDir override individual theme files, and the
+	// built-in "default" theme fills in anything neither provides.
+	Theme     string `toml:"theme"`
+	ThemesDir string `toml:"themes_dir"`
+
+	// ChromaLight and ChromaDark name the chroma styles used for code
+	// blocks in the light and dark theme respectively.
+	ChromaLight string `toml:"chroma_light"`
+	ChromaDark  string `toml:"chroma_dark"`
+
+	// ImageWidths are the widths local images are resized to for srcset;
+	// processed variants are cached under CacheDir between builds.
+	ImageWidths []int  `toml:"image_widths"`
+	CacheDir    string `toml:"cache_dir"`
+
+	// CheckTimeout bounds each external probe of the check command;
+	// CheckProxy optionally routes those probes through an HTTP endpoint.
+	CheckTimeout time.Duration `toml:"check_timeout"`
+	CheckProxy   string        `toml:"check_proxy"`
+
+	// ExternalLinkAllowlist lists hosts besides BaseURL whose links are not
+	// treated as external; ExternalLinkIcon marks external links with an icon.
+	ExternalLinkAllowlist []string `toml:"external_link_allowlist"`
+	ExternalLinkIcon      bool     `toml:"external_link_icon"`
+
+	// Gitignore controls how the build maintains .gitignore: "block" keeps a
+	// marked section listing build outputs up to date, "create" only writes
+	// the file when it is missing, and "off" never touches it.
+	Gitignore string `toml:"gitignore"`
+
+	// LinksFile is the TOML file listing the friend links page.
+	LinksFile string `toml:"links_file"`
+
+	// AuthorsFile lists the [[authors]] posts can be credited to; each gets
+	// a page under AuthorsDir. Posts without an authors field are credited
+	// to DefaultAuthor.
+	AuthorsFile   string `toml:"authors_file"`
+	AuthorsDir    string `toml:"authors_dir"`
+	DefaultAuthor string `toml:"default_author"`
+
+	// Permalinks maps a section name, or "pages" for standalone pages, to
+	// a URL pattern built from :section, :slug, :title, :year, :month and
+	// :day, e.g. "/:year/:month/:slug/". Sections default to
+	// "/:section/:slug.html" and pages to "/:slug.html".
+	Permalinks map[string]string `toml:"permalinks"`
+
+	// RedirectsFile optionally lists [[redirects]] from/to pairs that get
+	// redirect stubs alongside the aliases from front matter.
+	RedirectsFile string `toml:"redirects_file"`
+
+	// DefaultLanguage is the code of the language rendered at the site
+	// root; Languages configures it and any others, which render below
+	// /<code>/ and are picked up from content files named like redis.en.md.
+	// UI strings come from i18n/<code>.toml in I18nDir and the theme.
+	DefaultLanguage string                    `toml:"default_language"`
+	Languages       map[string]LanguageConfig `toml:"languages"`
+
+	// GitLastmod dates entries without a lastmod front matter field by the
+	// last commit that touched their file, read from the site's .git.
+	GitLastmod bool `toml:"git_lastmod"`
+}
+
+const configFile = "config.toml"
 
 func defaultConfig() Config {
 	return Config{
-		SiteTitle:    "yumosx's 写字的地方",
-		BaseURL:      "https://yumosx.github.io",
-		ContentDir:   "content",
-		TemplatesDir: "templates",
-		PublicDir:    "public",
-		StaticDir:    "static",
+		SiteTitle:       "yumosx's 写字的地方",
+		BaseURL:         "https://yumosx.github.io",
+		ContentDir:      "content",
+		TemplatesDir:    "templates",
+		PublicDir:       "public",
+		StaticDir:       "static",
+		PagesDir:        "pages",
+		SeriesDir:       "series",
+		DataDir:         "data",
+		I18nDir:         "i18n",
+		Theme:           builtinThemeName,
+		ThemesDir:       "themes",
+		ChromaLight:     "github",
+		ChromaDark:      "github-dark",
+		ImageWidths:     []int{480, 960, 1600},
+		CacheDir:        ".cache",
+		CheckTimeout:    10 * time.Second,
+		Gitignore:       "block",
+		LinksFile:       "data/links.toml",
+		AuthorsFile:     "data/authors.toml",
+		AuthorsDir:      "authors",
+		DefaultAuthor:   "yumosx",
+		RedirectsFile:   "redirects.toml",
+		DefaultLanguage: "zh",
+		GitLastmod:      true,
+		Languages: map[string]LanguageConfig{
+			"zh": {Locale: "zh-CN"},
+		},
+	}
+}
+
+// loadConfig overlays the optional site config file on top of the defaults.
+func loadConfig(path string) (Config, error) {
+	cfg := defaultConfig()
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		return cfg, fmt.Errorf("解析 %s: %w", path, err)
+	}
+	return cfg, cfg.validate()
+}
+
+func (c Config) validate() error {
+	if err := c.validateTheme(); err != nil {
+		return err
+	}
+	for _, name := range []string{c.ChromaLight, c.ChromaDark} {
+		if err := validateChromaStyle(name); err != nil {
+			return err
+		}
+	}
+	switch c.Gitignore {
+	case "block", "create", "off":
+	default:
+		return fmt.Errorf("gitignore 只能是 block、create 或 off: %q", c.Gitignore)
+	}
+	if err := c.validateLanguages(); err != nil {
+		return err
+	}
+	for key, pattern := range c.Permalinks {
+		if err := validatePermalink(key, pattern); err != nil {
+			return err
+		}
+	}
+	for _, w := range c.ImageWidths {
+		if w <= 0 {
+			return fmt.Errorf("image_widths 必须为正数: %d", w)
+		}
 	}
+	return nil
 }
 
 // Generator wires filesystem layout, parsing, and rendering.
 type Generator struct {
-	cfg Config
+	cfg        Config
+	md         goldmark.Markdown
+	images     *imagePipeline
+	shortcodes *template.Template
+	templates  *templateSet
+
+	// links are the friend links, loaded and validated once for all
+	// languages.
+	links []Link
+
+	diags  *diagnostics
+	strict bool
+
+	// outDir is where the current build renders to: a staging directory
+	// that replaces PublicDir once everything succeeded.
+	outDir string
+
+	// templatesFS, staticFS and i18nFS layer the site directories over the
+	// theme.
+	templatesFS fs.FS
+	staticFS    fs.FS
+	i18nFS      fs.FS
 }
 
 func NewGenerator(cfg Config) *Generator {
-	return &Generator{cfg: cfg}
+	staticFS := themeLayers(cfg, "static", cfg.StaticDir)
+	diags := &diagnostics{}
+	images := newImagePipeline(cfg, staticFS, diags)
+	return &Generator{
+		cfg:         cfg,
+		md:          newMarkdown(cfg, images),
+		images:      images,
+		diags:       diags,
+		templatesFS: themeLayers(cfg, "templates", cfg.TemplatesDir),
+		staticFS:    staticFS,
+		i18nFS:      themeLayers(cfg, "i18n", cfg.I18nDir),
+	}
 }
 
 func main() {
-	g := NewGenerator(defaultConfig())
-	if err := g.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "生成失败: %v\n", err)
+	cfg, err := loadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "读取配置: %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("博客生成成功！请查看 public 目录")
+	if err := runCommand(cfg, os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// runCommand dispatches the optional subcommand; no arguments means build.
+func runCommand(cfg Config, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return buildCommand(cfg, args)
+	}
+	switch args[0] {
+	case "chroma-css":
+		return chromaCSSCommand(cfg, args[1:])
+	case "check":
+		return checkCommand(cfg, args[1:])
+	case "init":
+		return initCommand(cfg, args[1:])
+	case "gitignore":
+		return gitignoreCommand(cfg, args[1:])
+	case "links":
+		return linksCommand(cfg, args[1:])
+	default:
+		return fmt.Errorf("未知命令 %q", args[0])
+	}
+}
+
+// chromaCSSCommand prints the code highlighting stylesheet for a style
+// pair: `chroma-css [light] [dark]`, defaulting to the configured styles.
+func chromaCSSCommand(cfg Config, args []string) error {
+	light, dark := cfg.ChromaLight, cfg.ChromaDark
+	if len(args) > 0 {
+		light = args[0]
+	}
+	if len(args) > 1 {
+		dark = args[1]
+	}
+	css, err := buildChromaCSS(light, dark)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Print(css)
+	return err
 }
 
-// Run performs full build: dirs, optional defaults, posts, public tree, HTML.
+// Run performs full build: dirs, posts, public tree, HTML.
 func (g *Generator) Run() error {
-	if err := g.ensureDirs(); err != nil {
+	if err := g.cfg.validate(); err != nil {
 		return err
 	}
-	if err := g.writeDefaultAssets(); err != nil {
+	if err := g.ensureDirs(); err != nil {
 		return err
 	}
 
-	site := &Site{
-		Title:   g.cfg.SiteTitle,
-		BaseURL: g.cfg.BaseURL,
+	data, err := loadData(g.cfg.DataDir)
+	if err != nil {
+		return fmt.Errorf("加载数据: %w", err)
+	}
+	languages, err := g.loadLanguages()
+	if err != nil {
+		return fmt.Errorf("加载语言: %w", err)
+	}
+	authors, err := loadAuthors(g.cfg.AuthorsFile)
+	if err != nil {
+		return fmt.Errorf("读取作者: %w", err)
+	}
+	g.validateAuthors(authors)
+	if g.links, err = loadLinks(g.cfg.LinksFile); err != nil {
+		return fmt.Errorf("读取友链: %w", err)
 	}
-	posts, err := g.loadPosts()
+	g.validateLinks(g.links)
+	sections, err := g.loadSections()
 	if err != nil {
 		return fmt.Errorf("加载文章: %w", err)
 	}
-	sort.Slice(posts, func(i, j int) bool {
-		timeI, _ := time.Parse("2006-01-02", posts[i].Date)
-		timeJ, _ := time.Parse("2006-01-02", posts[j].Date)
-		return timeI.After(timeJ)
-	})
-	site.Posts = posts
+	pages, err := g.loadContent(g.cfg.PagesDir, kindPage, "")
+	if err != nil {
+		return fmt.Errorf("加载页面: %w", err)
+	}
+	if err := g.loadShortcodes(); err != nil {
+		return err
+	}
 
-	if err := g.preparePublicDir(); err != nil {
+	var all []Post
+	for _, section := range sections {
+		all = append(all, section.Posts...)
+	}
+	all = append(all, pages...)
+	g.checkPermalinks(all)
+	g.applyLastmod(all)
+	g.assignAuthors(all, authors)
+	linkTranslations(all, languages)
+	rest := all
+	for i := range sections {
+		n := len(sections[i].Posts)
+		sections[i].Posts, rest = rest[:n:n], rest[n:]
+	}
+	pages = rest
+
+	var sites []*Site
+	for _, lang := range languages {
+		site, err := g.buildSite(lang, languages, data, authors, sections, pages)
+		if err != nil {
+			return err
+		}
+		sites = append(sites, site)
+	}
+	g.checkRoutes(sites)
+
+	if g.templates, err = g.loadTemplates(); err != nil {
 		return err
 	}
-	if err := g.renderIndex(site); err != nil {
+	if err := g.stageOutput(); err != nil {
 		return err
 	}
-	if err := g.renderPosts(site); err != nil {
+	if err := g.renderSites(sites); err != nil {
+		g.discardOutput()
 		return err
 	}
-	if err := g.renderLinks(site); err != nil {
+	if err := g.diags.check(g.strict); err != nil {
+		g.discardOutput()
+		return err
+	}
+	if err := g.commitOutput(); err != nil {
+		g.discardOutput()
 		return err
 	}
-	if err := g.writeGitignore(); err != nil {
+	if err := g.updateGitignore(false); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (g *Generator) ensureDirs() error {
-	for _, dir := range []string{
-		g.cfg.ContentDir,
-		g.cfg.TemplatesDir,
-		g.cfg.PublicDir,
-		g.cfg.StaticDir,
-	} {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("创建目录 %s: %w", dir, err)
+// buildSite picks the entries of one language out of every section and the
+// pages, and converts them together so cross-references and backlinks stay
+// within the language. Cross-references a translation cannot resolve fall
+// back to the default language.
+func (g *Generator) buildSite(lang *Language, languages []*Language, data map[string]interface{}, authors []Author, sections []Section, pages []Post) (*Site, error) {
+	site := &Site{
+		Title:      lang.Title,
+		BaseURL:    g.cfg.BaseURL,
+		Data:       data,
+		Lang:       lang,
+		Languages:  languages,
+		allAuthors: authors,
+	}
+
+	var all []Post
+	var langSections []Section
+	for _, section := range sections {
+		s := section.inLanguage(lang, g.cfg.DefaultLanguage)
+		if len(s.Posts) == 0 && s.Name != postsSection {
+			continue
 		}
+		langSections = append(langSections, s)
+		all = append(all, s.Posts...)
 	}
-	return nil
-}
+	var langPages []Post
+	for _, p := range pages {
+		if p.Lang == lang.Code {
+			langPages = append(langPages, p)
+		}
+	}
+	g.checkPageSlugs(langSections[0].Posts, langPages)
 
-func (g *Generator) writeDefaultAssets() error {
-	mainTmpl := `<!DOCTYPE html>
-<html lang="zh-CN">
-<head>
-	<meta charset="UTF-8">
-	<meta name="viewport" content="width=device-width, initial-scale=1.0">
-	<title>{{.Site.Title}} - {{.Title}}</title>
-	<link rel="preconnect" href="https://fonts.googleapis.com">
-	<link rel="preconnect" href="https://fonts.gstatic.com" crossorigin>
-	<link href="https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&family=JetBrains+Mono:wght@400;500&display=swap" rel="stylesheet">
-	<link rel="stylesheet" href="/static/style.css">
-	<link rel="alternate" type="application/rss+xml" href="/feed.xml" title="{{.Site.Title}} RSS Feed">
-	<script>(function(){try{var t=localStorage.getItem('blog-theme');if(t==='dark'||t==='light')document.documentElement.setAttribute('data-theme',t);else if(window.matchMedia&&window.matchMedia('(prefers-color-scheme: dark)').matches)document.documentElement.setAttribute('data-theme','dark')}catch(e){}})();</script>
-</head>
-<body>
-	<header class="site-header">
-		<h1><a href="/">{{.Site.Title}}</a></h1>
-		<nav class="site-nav">
-			<a href="/">首页</a>
-			<a href="/links.html">友链</a>
-		</nav>
-		<button id="theme-toggle" class="theme-toggle" aria-label="切换主题">🌙</button>
-	</header>
-	<main>
-		{{template "content" .}}
-	</main>
-	<footer>
-		<p>© {{now.Format "2006"}} {{.Site.Title}}</p>
-	</footer>
-	<script src="/static/theme.js"></script>
-	<script src="/static/profile.js"></script>
-</body>
-</html>`
-
-	indexTmpl := `{{define "content"}}
-	<section class="profile-card" aria-label="个人简介">
-		<div class="profile-row profile-header">
-			<div class="profile-identity">
-				<img class="profile-avatar" src="https://github.com/yumosx.png" alt="Ian Wang" width="72" height="72" loading="eager">
-				<div class="profile-name-block">
-					<h2 class="profile-name">Ian Wang<span class="typing-cursor" aria-hidden="true"></span></h2>
-					<p class="profile-handle">[@yumosx]</p>
-				</div>
-			</div>
-			<div class="profile-actions">
-				<button type="button" id="copy-profile" class="icon-btn" aria-label="复制主页链接" title="复制链接">
-					<svg width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true"><rect x="9" y="9" width="13" height="13" rx="2"/><path d="M5 15H4a2 2 0 0 1-2-2V4a2 2 0 0 1 2-2h9a2 2 0 0 1 2 2v1"/></svg>
-				</button>
-			</div>
-		</div>
-
-		<nav class="profile-row profile-links" aria-label="社交链接">
-			<a href="https://github.com/yumosx" class="profile-link" target="_blank" rel="noopener noreferrer">
-				<svg class="link-icon" width="16" height="16" viewBox="0 0 24 24" fill="currentColor" aria-hidden="true"><path d="M12 0C5.37 0 0 5.37 0 12c0 5.31 3.435 9.795 8.205 11.385.6.105.825-.255.825-.57 0-.285-.015-1.23-.015-2.235-3.015.555-3.795-.735-4.035-1.41-.135-.345-.72-1.41-1.23-1.695-.42-.225-1.02-.78-.015-.795.945-.015 1.62.87 1.845 1.23 1.08 1.815 2.805 1.305 3.495.99.105-.78.42-1.305.765-1.605-2.67-.3-5.46-1.335-5.46-5.925 0-1.305.465-2.385 1.23-3.225-.12-.3-.54-1.53.12-3.18 0 0 1.005-.315 3.3 1.23.96-.27 1.98-.405 3-.405s2.04.135 3 .405c2.295-1.56 3.3-1.23 3.3-1.23.66 1.65.24 2.88.12 3.18.765.84 1.23 1.905 1.23 3.225 0 4.605-2.805 5.625-5.475 5.925.435.375.81 1.095.81 2.22 0 1.605-.015 2.895-.015 3.3 0 .315.225.69.825.57A12.02 12.02 0 0 0 24 12c0-6.63-5.37-12-12-12z"/></svg>
-				<span>yumosx</span>
-			</a>
-			<a href="https://opentelemetry.io" class="profile-link" target="_blank" rel="noopener noreferrer">
-				<svg class="link-icon" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true"><circle cx="12" cy="12" r="10"/><path d="M12 6v6l4 2"/></svg>
-				<span>OpenTelemetry</span>
-			</a>
-			<a href="/links.html" class="profile-link">
-				<svg class="link-icon" width="16" height="16" viewBox="0 0 24 24" fill="none" stroke="currentColor" stroke-width="2" stroke-linecap="round" stroke-linejoin="round" aria-hidden="true"><line x1="8" y1="6" x2="21" y2="6"/><line x1="8" y1="12" x2="21" y2="12"/><line x1="8" y1="18" x2="21" y2="18"/><line x1="3" y1="6" x2="3.01" y2="6"/><line x1="3" y1="12" x2="3.01" y2="12"/><line x1="3" y1="18" x2="3.01" y2="18"/></svg>
-				<span>友链</span>
-			</a>
-		</nav>
-
-		<div class="profile-divider" aria-hidden="true"></div>
-
-		<div class="profile-row profile-bio">
-			<p class="profile-intro">Hi there, I'm Ian Wang (<a href="https://github.com/yumosx" target="_blank" rel="noopener noreferrer">@yumosx</a>). Gopher · OpenTelemetry CNCF Member · Open-source Addict.</p>
-			<p class="profile-status">
-				<span class="status-indicator" aria-hidden="true"></span>
-				Currently making observability simpler, faster, and more fun in Go.
-			</p>
-		</div>
-	</section>
-
-	<section class="posts-section">
-		<h2 class="section-title">博客文章</h2>
-		<ul class="post-list">
-		{{range .Posts}}
-			<li>
-				<a href="/posts/{{.Slug}}.html">{{.Title}}</a>
-				<span class="post-date">{{.Date}}</span>
-				<p>{{.Summary}}</p>
-			</li>
-		{{end}}
-		</ul>
-	</section>
-{{end}}`
-
-	postTmpl := `{{define "content"}}
-	<article class="post">
-		<h2>{{.Title}}</h2>
-		<div class="post-meta">{{.Date}}</div>
-		<div class="post-content">{{.Content}}</div>
-	</article>
-{{end}}`
-
-	css, err := buildDefaultCSS()
-	if err != nil {
-		return fmt.Errorf("生成样式: %w", err)
-	}
-
-	themeJS := `(function() {
-	var KEY = 'blog-theme';
-	function setTheme(t) {
-		document.documentElement.setAttribute('data-theme', t);
-		try { localStorage.setItem(KEY, t); } catch(e) {}
-		updateIcon(t);
-	}
-	function getTheme() {
-		try {
-			var saved = localStorage.getItem(KEY);
-			if (saved === 'dark' || saved === 'light') return saved;
-		} catch(e) {}
-		if (window.matchMedia && window.matchMedia('(prefers-color-scheme: dark)').matches) return 'dark';
-		return 'light';
-	}
-	function updateIcon(t) {
-		var btn = document.getElementById('theme-toggle');
-		if (!btn) return;
-		btn.textContent = t === 'dark' ? '\u2600\uFE0F' : '\uD83C\uDF19';
-		btn.setAttribute('aria-label', t === 'dark' ? '切换到亮色模式' : '切换到暗黑模式');
-	}
-	function init() {
-		var t = getTheme();
-		setTheme(t);
-		var btn = document.getElementById('theme-toggle');
-		if (btn) btn.addEventListener('click', function() {
-			var cur = document.documentElement.getAttribute('data-theme');
-			setTheme(cur === 'dark' ? 'light' : 'dark');
-		});
-	}
-	if (document.readyState === 'loading') {
-		document.addEventListener('DOMContentLoaded', init);
-	} else {
-		init();
-	}
-})();`
-
-	profileJS := `(function () {
-	function initCopyButton() {
-		var btn = document.getElementById('copy-profile');
-		if (!btn) return;
-
-		btn.addEventListener('click', function () {
-			var url = window.location.origin + '/';
-			if (navigator.clipboard && navigator.clipboard.writeText) {
-				navigator.clipboard.writeText(url).then(showCopied).catch(fallbackCopy);
-			} else {
-				fallbackCopy(url);
+	all = append(all, langPages...)
+	var fallback []Post
+	if lang.Code != g.cfg.DefaultLanguage {
+		for _, section := range sections {
+			for _, p := range section.Posts {
+				if p.Lang == g.cfg.DefaultLanguage {
+					fallback = append(fallback, p)
+				}
 			}
-		});
-
-		function fallbackCopy(text) {
-			var ta = document.createElement('textarea');
-			ta.value = text;
-			ta.style.position = 'fixed';
-			ta.style.opacity = '0';
-			document.body.appendChild(ta);
-			ta.select();
-			try {
-				document.execCommand('copy');
-				showCopied();
-			} catch (e) {}
-			document.body.removeChild(ta);
 		}
-
-		function showCopied() {
-			btn.classList.add('copied');
-			btn.setAttribute('aria-label', '已复制');
-			setTimeout(function () {
-				btn.classList.remove('copied');
-				btn.setAttribute('aria-label', '复制主页链接');
-			}, 2000);
+		for _, p := range pages {
+			if p.Lang == g.cfg.DefaultLanguage {
+				fallback = append(fallback, p)
+			}
 		}
 	}
-
-	if (document.readyState === 'loading') {
-		document.addEventListener('DOMContentLoaded', initCopyButton);
-	} else {
-		initCopyButton();
+	if err := g.convertPosts(all, fallback); err != nil {
+		return nil, err
 	}
-})();`
+	rest := all
+	for i := range langSections {
+		n := len(langSections[i].Posts)
+		langSections[i].Posts, rest = rest[:n:n], rest[n:]
+	}
+	site.Sections = langSections
+	site.Posts = langSections[0].Posts
+	site.Pages = rest
+	site.Menu = buildMenu(site.Pages)
+	site.Series = buildSeries(langSections, path.Join(strings.TrimPrefix(lang.Prefix, "/"), g.cfg.SeriesDir))
+	site.Authors = buildAuthors(authors, langSections, path.Join(strings.TrimPrefix(lang.Prefix, "/"), g.cfg.AuthorsDir))
+	return site, nil
+}
 
-	paths := map[string]string{
-		filepath.Join(g.cfg.TemplatesDir, "main.html"):  mainTmpl,
-		filepath.Join(g.cfg.TemplatesDir, "index.html"): indexTmpl,
-		filepath.Join(g.cfg.TemplatesDir, "post.html"):  postTmpl,
-		filepath.Join(g.cfg.StaticDir, "style.css"):     css,
-		filepath.Join(g.cfg.StaticDir, "theme.js"):      themeJS,
-		filepath.Join(g.cfg.StaticDir, "profile.js"):    profileJS,
+// renderSites writes the whole public tree into outDir: the shared static
+// files once, then every language's pages and the sitemap, then the
+// redirect stubs.
+func (g *Generator) renderSites(sites []*Site) error {
+	if err := g.preparePublicDir(); err != nil {
+		return err
 	}
-	for path, body := range paths {
-		if _, err := os.Stat(path); err == nil {
-			continue
+	for _, site := range sites {
+		if err := g.renderSite(site); err != nil {
+			return err
 		}
-		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
-			return fmt.Errorf("写入 %s: %w", path, err)
+	}
+	if err := g.renderSitemap(sites); err != nil {
+		return err
+	}
+	return g.renderRedirects(sites)
+}
+
+// siteDir is the output directory of a site's language.
+func (g *Generator) siteDir(site *Site) string {
+	return filepath.Join(g.outDir, filepath.FromSlash(site.Lang.Prefix))
+}
+
+// renderSite writes the pages of one language.
+func (g *Generator) renderSite(site *Site) error {
+	if err := g.renderIndex(site); err != nil {
+		return err
+	}
+	if err := g.renderPosts(site); err != nil {
+		return err
+	}
+	if err := g.renderSections(site); err != nil {
+		return err
+	}
+	if err := g.renderSeries(site); err != nil {
+		return err
+	}
+	if err := g.renderAuthors(site); err != nil {
+		return err
+	}
+	if err := g.renderLinks(site); err != nil {
+		return err
+	}
+	if err := g.renderPages(site); err != nil {
+		return err
+	}
+	return g.renderArchive(site)
+}
+
+func (g *Generator) ensureDirs() error {
+	for _, dir := range []string{
+		g.cfg.ContentDir,
+	} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("创建目录 %s: %w", dir, err)
 		}
 	}
 	return nil
 }
 
-func (g *Generator) loadPosts() ([]Post, error) {
-	files, err := os.ReadDir(g.cfg.ContentDir)
+// loadContent parses the markdown files and page bundles directly in dir as
+// entries of the given kind and section. A missing dir holds no entries.
+func (g *Generator) loadContent(dir, kind, section string) ([]Post, error) {
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	var posts []Post
+	var paths []string
 	for _, file := range files {
-		if filepath.Ext(file.Name()) != ".md" {
-			continue
+		path := filepath.Join(dir, file.Name())
+		if file.IsDir() {
+			paths = append(paths, g.cfg.bundleIndexes(path)...)
+		} else if filepath.Ext(file.Name()) == ".md" && !strings.HasPrefix(file.Name(), "_") {
+			paths = append(paths, path)
 		}
-		path := filepath.Join(g.cfg.ContentDir, file.Name())
-		post, err := parsePost(path)
+	}
+
+	var posts []Post
+	for _, path := range paths {
+		post, err := g.parsePost(path, kind, section)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "解析文章 %s: %v\n", file.Name(), err)
+			g.diags.Errorf(path, 0, "解析文章: %v", err)
 			continue
 		}
 		posts = append(posts, post)
@@ -367,22 +663,27 @@ func (g *Generator) loadPosts() ([]Post, error) {
 	return posts, nil
 }
 
-func parsePost(filePath string) (Post, error) {
-	var post Post
-
-	content, err := os.ReadFile(filePath)
-	if err != nil {
-		return post, err
-	}
+// frontMatter is the "Key: value" block between the leading --- lines of a
+// content file. Keys are lowercased; line records where each key was set.
+type frontMatter struct {
+	fields   map[string]string
+	line     map[string]int
+	body     string // markdown after the front matter
+	bodyLine int    // file line body starts on
+}
 
+func parseFrontMatter(content []byte) frontMatter {
+	fm := frontMatter{fields: make(map[string]string), line: make(map[string]int)}
 	scanner := bufio.NewScanner(bytes.NewReader(content))
 	inFrontMatter := false
 	frontMatterEnd := 0
+	frontMatterLines := 0
 	foundFrontMatter := false
 
 	for scanner.Scan() {
 		line := scanner.Text()
 		frontMatterEnd += len(line) + 1
+		frontMatterLines++
 
 		if line == "---" {
 			if !inFrontMatter {
@@ -400,58 +701,151 @@ func parsePost(filePath string) (Post, error) {
 			if len(parts) != 2 {
 				continue
 			}
-			switch parts[0] {
-			case "Title":
-				post.Title = parts[1]
-			case "Date":
-				post.Date = parts[1]
-			}
+			key := strings.ToLower(parts[0])
+			fm.fields[key] = parts[1]
+			fm.line[key] = frontMatterLines
 		}
 	}
 
 	if !foundFrontMatter {
 		frontMatterEnd = 0
+		frontMatterLines = 0
+	}
+	fm.body = string(content[frontMatterEnd:])
+	fm.bodyLine = frontMatterLines + 1
+	return fm
+}
+
+func (g *Generator) parsePost(filePath, kind, section string) (Post, error) {
+	post := Post{File: filePath, Kind: kind, Section: section, Layout: kind}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return post, err
+	}
+
+	fm := parseFrontMatter(content)
+	post.Title = fm.fields["title"]
+	post.Date = fm.fields["date"]
+	post.Menu = strings.TrimSpace(fm.fields["menu"])
+	post.Weight, _ = strconv.Atoi(strings.TrimSpace(fm.fields["weight"]))
+	post.Series = strings.TrimSpace(fm.fields["series"])
+	post.Part, _ = strconv.Atoi(strings.TrimSpace(fm.fields["part"]))
+	post.aliasesLine = fm.line["aliases"]
+	for _, alias := range strings.Split(fm.fields["aliases"], ",") {
+		if alias = strings.TrimSpace(alias); alias != "" {
+			post.Aliases = append(post.Aliases, alias)
+		}
+	}
+	for _, author := range strings.Split(fm.fields["authors"], ",") {
+		if author = strings.TrimSpace(author); author != "" {
+			post.Authors = append(post.Authors, author)
+		}
+	}
+	if layout, ok := fm.fields["layout"]; ok {
+		post.Layout = strings.TrimSuffix(strings.TrimSpace(layout), ".html")
+		post.explicitLayout = true
+	}
+
+	base, lang := g.cfg.splitLang(strings.TrimSuffix(filepath.Base(filePath), ".md"))
+	post.Slug, post.Lang = base, lang
+	if base+".md" == bundleIndex {
+		post.BundleDir = filepath.Dir(filePath)
+		post.Slug = filepath.Base(post.BundleDir)
+	}
+	post.translationKey = kind + "/" + section + "/" + post.Slug
+	if slug := strings.TrimSpace(fm.fields["slug"]); slug != "" {
+		if strings.ContainsAny(slug, `/\`) || slug == "." || slug == ".." {
+			return post, fmt.Errorf("slug 不能包含路径: %q", slug)
+		}
+		post.Slug = slug
+	}
+	pattern := g.cfg.permalinkPattern(post)
+	if usesDate(pattern) && post.Time().IsZero() {
+		g.diags.Errorf(filePath, fm.line["date"], "永久链接 %s 需要有效的日期 (date)", pattern)
+	}
+	post.url = expandPermalink(pattern, post)
+	if post.Lang != g.cfg.DefaultLanguage {
+		post.url = "/" + post.Lang + post.url
+	}
+	if post.BundleDir != "" {
+		post.Resources, err = bundleResources(post.BundleDir)
+		if err != nil {
+			return post, fmt.Errorf("读取资源: %w", err)
+		}
+		for _, rel := range post.Resources {
+			g.images.AddSource(post.ResourceURL()+rel, filepath.Join(post.BundleDir, filepath.FromSlash(rel)))
+		}
+	}
+
+	if strings.TrimSpace(post.Title) == "" {
+		g.diags.Warnf(filePath, 0, "缺少标题 (title)")
+	}
+	if kind == kindPost {
+		if strings.TrimSpace(post.Date) == "" {
+			g.diags.Warnf(filePath, 0, "缺少日期 (date)")
+		} else if _, err := parsePostDate(post.Date); err != nil {
+			g.diags.Warnf(filePath, fm.line["date"], "无法解析日期 %q", post.Date)
+		}
+	}
+	if lastmod := strings.TrimSpace(fm.fields["lastmod"]); lastmod != "" {
+		if post.Lastmod, err = parsePostDate(lastmod); err != nil {
+			g.diags.Warnf(filePath, fm.line["lastmod"], "无法解析日期 %q", lastmod)
+		}
 	}
 
-	post.Slug = strings.TrimSuffix(filepath.Base(filePath), ".md")
-	postContent := string(content[frontMatterEnd:])
-	post.Content = template.HTML(convertMarkdownToHTML(postContent))
-	post.Summary = extractSummary(postContent)
+	post.body = fm.body
+	post.bodyLine = fm.bodyLine
+	post.Summary = extractSummary(stripWikiLinks(stripShortcodes(post.body)))
 
 	return post, nil
 }
 
-var (
-	markdownConverter = goldmark.New(
-		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+func newMarkdown(cfg Config, images *imagePipeline) goldmark.Markdown {
+	return goldmark.New(
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithInlineParsers(util.Prioritized(wikiLinkParser{}, 150)),
+			parser.WithASTTransformers(
+				util.Prioritized(bundleLinkTransformer{}, 100),
+				util.Prioritized(imageSourceTransformer{}, 150),
+				util.Prioritized(xrefTransformer{}, 200),
+				util.Prioritized(newExternalLinkTransformer(cfg), 300),
+			),
+		),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(&imageRenderer{images: images}, 100),
+		)),
 		goldmark.WithExtensions(
 			extension.GFM,
 			highlighting.NewHighlighting(
-				highlighting.WithStyle("github"),
+				highlighting.WithStyle(cfg.ChromaLight),
 				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
 			),
 		),
 	)
-	externalLinkRe = regexp.MustCompile(`<a href="(https?://[^"]*)"`)
-)
+}
 
-func convertMarkdownToHTML(markdownStr string) string {
+func convertMarkdownToHTML(md goldmark.Markdown, markdownStr string, opts ...parser.ParseOption) string {
 	var buf bytes.Buffer
-	if err := markdownConverter.Convert([]byte(markdownStr), &buf); err != nil {
+	if err := md.Convert([]byte(markdownStr), &buf, opts...); err != nil {
 		return markdownStr
 	}
-	return addExternalLinkTarget(buf.String())
+	return buf.String()
 }
 
-func addExternalLinkTarget(html string) string {
-	return externalLinkRe.ReplaceAllString(html, `<a href="$1" target="_blank" rel="noopener noreferrer"`)
+func validateChromaStyle(name string) error {
+	if _, ok := styles.Registry[name]; !ok {
+		return fmt.Errorf("找不到 chroma 样式 %q, 可用的样式: %s", name, strings.Join(styles.Names(), ", "))
+	}
+	return nil
 }
 
 func chromaStyleCSS(styleName, scope string) (string, error) {
-	style := styles.Get(styleName)
-	if style == nil {
-		return "", fmt.Errorf("chroma style %q not found", styleName)
+	if err := validateChromaStyle(styleName); err != nil {
+		return "", err
 	}
+	style := styles.Get(styleName)
 	var buf bytes.Buffer
 	formatter := chromahtml.New(chromahtml.WithClasses(true))
 	if err := formatter.WriteCSS(&buf, style); err != nil {
@@ -465,139 +859,18 @@ func chromaStyleCSS(styleName, scope string) (string, error) {
 	return css, nil
 }
 
-func buildDefaultCSS() (string, error) {
-	lightChroma, err := chromaStyleCSS("github", ":root:not([data-theme='dark']) .post-content")
+// buildChromaCSS renders the code highlighting rules for a light/dark style
+// pair, each scoped to the matching data-theme.
+func buildChromaCSS(light, dark string) (string, error) {
+	lightChroma, err := chromaStyleCSS(light, ":root:not([data-theme='dark']) .post-content")
 	if err != nil {
 		return "", err
 	}
-	darkChroma, err := chromaStyleCSS("github-dark", "[data-theme='dark'] .post-content")
+	darkChroma, err := chromaStyleCSS(dark, "[data-theme='dark'] .post-content")
 	if err != nil {
 		return "", err
 	}
-
-	return `:root {
-	--bg: #ffffff;
-	--text: #333333;
-	--text-secondary: #777777;
-	--border: #eeeeee;
-	--link: #333333;
-	--link-hover: #007acc;
-	--code-bg: #f5f5f5;
-}
-
-[data-theme='dark'] {
-	--bg: #1a1a2e;
-	--text: #e0e0e0;
-	--text-secondary: #999999;
-	--border: #2d2d44;
-	--link: #64b5f6;
-	--link-hover: #90caf9;
-	--code-bg: #252540;
-}
-
-body {
-	font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, Oxygen, Ubuntu, Cantarell, sans-serif;
-	line-height: 1.6;
-	color: var(--text);
-	background-color: var(--bg);
-	max-width: 800px;
-	margin: 0 auto;
-	padding: 20px;
-	transition: background-color 0.3s, color 0.3s;
-}
-
-header {
-	border-bottom: 1px solid var(--border);
-	padding-bottom: 20px;
-	margin-bottom: 30px;
-	display: flex;
-	justify-content: space-between;
-	align-items: center;
-}
-	h1 { margin: 0; }
-
-.site-nav {
-	display: flex;
-	gap: 1.2em;
-	font-size: 0.95em;
-}
-	.site-nav a:hover { color: var(--link-hover); }
-
-	.theme-toggle {
-		background: none; border: none; font-size: 1.4em;
-		cursor: pointer; padding: 4px 8px; border-radius: 6px;
-		transition: background-color 0.2s;
-	}
-		.theme-toggle:hover { background-color: var(--border); }
-
-a { color: var(--link); text-decoration: none; }
-main { margin-bottom: 40px; }
-footer { border-top: 1px solid var(--border); padding-top: 20px; text-align: center; color: var(--text-secondary); }
-
-.post-list { list-style: none; padding: 0; }
-	.post-list li { margin-bottom: 30px; padding-bottom: 20px; border-bottom: 1px solid var(--border); }
-	.post-list a { font-size: 1.2em; font-weight: bold; display: block; margin-bottom: 5px; }
-	.post-list a:hover { color: var(--link-hover); }
-	.post-date { display: block; color: var(--text-secondary); font-size: 0.9em; margin-bottom: 10px; }
-
-.post { margin-bottom: 40px; }
-	.post-meta { color: var(--text-secondary); margin-bottom: 20px; }
-	.post-content { line-height: 1.8; }
-	.post-content h2 { margin-top: 40px; }
-	.post-content h3 { margin-top: 28px; }
-	.post-content blockquote {
-		margin: 1.2em 0;
-		padding: 0.5em 1em;
-		border-left: 4px solid var(--border);
-		color: var(--text-secondary);
-	}
-	.post-content table {
-		width: 100%;
-		border-collapse: collapse;
-		margin: 1.2em 0;
-		font-size: 0.95em;
-	}
-	.post-content th,
-	.post-content td {
-		border: 1px solid var(--border);
-		padding: 8px 12px;
-		text-align: left;
-	}
-	.post-content th { background-color: var(--code-bg); }
-	.post-content pre.chroma {
-		padding: 16px;
-		border-radius: 8px;
-		overflow-x: auto;
-		border: 1px solid var(--border);
-		margin: 1.2em 0;
-		font-family: 'Cascadia Code', 'JetBrains Mono', Consolas, 'Courier New', monospace;
-		font-size: 0.88em;
-		line-height: 1.55;
-	}
-	.post-content pre.chroma code {
-		background: none;
-		padding: 0;
-		border-radius: 0;
-		font-size: inherit;
-	}
-	.post-content :not(pre) > code {
-		background-color: var(--code-bg);
-		padding: 2px 6px;
-		border-radius: 4px;
-		font-family: 'Cascadia Code', 'JetBrains Mono', Consolas, 'Courier New', monospace;
-		font-size: 0.88em;
-	}
-	.post-content img { max-width: 100%; height: auto; border-radius: 8px; margin: 20px 0; display: block; }
-
-.link-list { list-style: none; padding: 0; }
-	.link-list li { margin-bottom: 24px; padding-bottom: 20px; border-bottom: 1px solid var(--border); }
-	.link-list a { display: block; margin-bottom: 4px; }
-	.link-list a:hover { color: var(--link-hover); }
-	.link-title { font-weight: bold; margin-right: 0.5em; }
-	.link-url { color: var(--text-secondary); font-size: 0.9em; }
-	.link-desc { display: block; color: var(--text-secondary); font-size: 0.9em; margin-top: 4px; }
-
-` + lightChroma + "\n" + darkChroma, nil
+	return lightChroma + "\n" + darkChroma, nil
 }
 
 func extractSummary(content string) string {
@@ -629,39 +902,21 @@ func extractSummary(content string) string {
 }
 
 func (g *Generator) preparePublicDir() error {
-	pub := g.cfg.PublicDir
+	pub := g.outDir
 	if err := os.MkdirAll(filepath.Join(pub, "posts"), 0755); err != nil {
 		return err
 	}
-	if err := copyDir(g.cfg.StaticDir, filepath.Join(pub, "static")); err != nil {
+	if err := copyFS(g.staticFS, filepath.Join(pub, "static")); err != nil {
 		return fmt.Errorf("复制静态资源: %w", err)
 	}
-	return nil
-}
-
-func copyDir(src, dst string) error {
-	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		relPath, _ := filepath.Rel(src, path)
-		dstPath := filepath.Join(dst, relPath)
-		if info.IsDir() {
-			return os.MkdirAll(dstPath, 0755)
-		}
-		data, err := os.ReadFile(path)
-		if err != nil {
-			return err
-		}
-		return os.WriteFile(dstPath, data, 0644)
-	})
-}
-
-func (g *Generator) blogTemplateFuncs() template.FuncMap {
-	return template.FuncMap{
-		"now":         time.Now,
-		"extractHost": extractHostFromURL,
+	if err := g.images.Publish(pub); err != nil {
+		return fmt.Errorf("发布图片: %w", err)
 	}
+	chromaCSS, err := buildChromaCSS(g.cfg.ChromaLight, g.cfg.ChromaDark)
+	if err != nil {
+		return fmt.Errorf("生成代码高亮样式: %w", err)
+	}
+	return os.WriteFile(filepath.Join(pub, "static", "chroma.css"), []byte(chromaCSS), 0644)
 }
 
 func extractHostFromURL(rawURL string) string {
@@ -672,31 +927,15 @@ func extractHostFromURL(rawURL string) string {
 	return u.Hostname()
 }
 
-func (g *Generator) parseLayoutWithFragment(fragmentPath string) (*template.Template, error) {
-	mainPath := filepath.Join(g.cfg.TemplatesDir, "main.html")
-	mainContent, err := os.ReadFile(mainPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取主模板 %s: %w", mainPath, err)
-	}
-	fragContent, err := os.ReadFile(fragmentPath)
-	if err != nil {
-		return nil, fmt.Errorf("读取片段 %s: %w", fragmentPath, err)
-	}
-	combined := string(mainContent) + string(fragContent)
-	tmpl, err := template.New("blog").Funcs(g.blogTemplateFuncs()).Parse(combined)
-	if err != nil {
-		return nil, fmt.Errorf("解析模板: %w", err)
-	}
-	return tmpl, nil
-}
-
 func (g *Generator) renderIndex(site *Site) error {
-	fragment := filepath.Join(g.cfg.TemplatesDir, "index.html")
-	tmpl, err := g.parseLayoutWithFragment(fragment)
+	tmpl, err := g.templates.Page("index")
 	if err != nil {
 		return err
 	}
-	outPath := filepath.Join(g.cfg.PublicDir, "index.html")
+	if err := os.MkdirAll(g.siteDir(site), 0755); err != nil {
+		return err
+	}
+	outPath := filepath.Join(g.siteDir(site), "index.html")
 	f, err := os.Create(outPath)
 	if err != nil {
 		return fmt.Errorf("创建 %s: %w", outPath, err)
@@ -704,9 +943,10 @@ func (g *Generator) renderIndex(site *Site) error {
 	defer f.Close()
 
 	ctx := map[string]interface{}{
-		"Site":  site,
-		"Title": site.Title,
-		"Posts": site.Posts,
+		"Site":         site,
+		"Title":        site.Title,
+		"Posts":        site.Posts,
+		"Translations": site.homeTranslations(),
 	}
 	if err := tmpl.Execute(f, ctx); err != nil {
 		return fmt.Errorf("渲染首页: %w", err)
@@ -715,98 +955,59 @@ func (g *Generator) renderIndex(site *Site) error {
 }
 
 func (g *Generator) renderPosts(site *Site) error {
-	fragment := filepath.Join(g.cfg.TemplatesDir, "post.html")
-	tmpl, err := g.parseLayoutWithFragment(fragment)
-	if err != nil {
-		return err
-	}
-
-	outDir := filepath.Join(g.cfg.PublicDir, "posts")
-	for _, post := range site.Posts {
-		outPath := filepath.Join(outDir, post.Slug+".html")
-		f, err := os.Create(outPath)
-		if err != nil {
-			return fmt.Errorf("创建 %s: %w", outPath, err)
-		}
-		ctx := map[string]interface{}{
-			"Site":    site,
-			"Title":   post.Title,
-			"Date":    post.Date,
-			"Content": post.Content,
-		}
-		if err := tmpl.Execute(f, ctx); err != nil {
-			f.Close()
-			return fmt.Errorf("渲染文章 %s: %w", post.Slug, err)
-		}
-		if err := f.Close(); err != nil {
-			return fmt.Errorf("关闭 %s: %w", outPath, err)
+	for _, section := range site.Sections {
+		for _, post := range section.Posts {
+			if err := g.renderEntry(site, post); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-func (g *Generator) renderLinks(site *Site) error {
-	fragment := filepath.Join(g.cfg.TemplatesDir, "links.html")
-	tmpl, err := g.parseLayoutWithFragment(fragment)
-	if err != nil {
-		return err
+// renderEntry writes a post or page to its URL with its layout, publishing
+// bundle resources next to it.
+func (g *Generator) renderEntry(site *Site, post Post) error {
+	layouts := []string{post.Layout}
+	if !post.explicitLayout && post.Kind == kindPost && post.Section != postsSection {
+		layouts = []string{post.Section + "/" + post.Layout, post.Layout}
 	}
-
-	var linksConfig struct {
-		Links []Link `toml:"links"`
+	tmpl, err := g.templates.Page(layouts[0], layouts[1:]...)
+	if err != nil {
+		return fmt.Errorf("文章 %s: %w", post.Slug, err)
 	}
-	if _, err := toml.DecodeFile("links.toml", &linksConfig); err != nil {
-		return fmt.Errorf("解析 links.toml: %w", err)
+	outPath := filepath.Join(g.outDir, filepath.FromSlash(outputFile(post.URL())))
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
 	}
-
-	outPath := filepath.Join(g.cfg.PublicDir, "links.html")
 	f, err := os.Create(outPath)
 	if err != nil {
 		return fmt.Errorf("创建 %s: %w", outPath, err)
 	}
-	defer f.Close()
-
 	ctx := map[string]interface{}{
-		"Site":  site,
-		"Title": "友链",
-		"Links": linksConfig.Links,
+		"Site":         site,
+		"Page":         post,
+		"Title":        post.Title,
+		"Date":         post.Date,
+		"Content":      post.Content,
+		"Backlinks":    post.Backlinks,
+		"Series":       site.seriesOf(post),
+		"Authors":      site.authorsOf(post),
+		"JSONLD":       g.entryJSONLD(site, post),
+		"Translations": post.Translations,
 	}
 	if err := tmpl.Execute(f, ctx); err != nil {
-		return fmt.Errorf("渲染友链页: %w", err)
+		f.Close()
+		return fmt.Errorf("渲染文章 %s: %w", post.Slug, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("关闭 %s: %w", outPath, err)
+	}
+	if post.BundleDir != "" {
+		resDir := filepath.Join(g.outDir, filepath.FromSlash(post.ResourceURL()))
+		if err := copyBundleResources(post, resDir); err != nil {
+			return fmt.Errorf("复制文章资源 %s: %w", post.Slug, err)
+		}
 	}
 	return nil
 }
-
-func (g *Generator) writeGitignore() error {
-	gitignore := `# Binaries
-*.exe
-*.exe~
-*.dll
-*.so
-*.dylib
-
-# Test binary
-*.test
-
-# Output of the go coverage tool
-*.out
-
-# Dependency directories
-vendor/
-
-# Build output
-public/
-
-# Environment variables
-.env
-.env.local
-
-# Editor directories and files
-.vscode/
-.idea/
-*.swp
-*.swo
-*~
-`
-	return os.WriteFile(".gitignore", []byte(gitignore), 0644)
-}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// stagingPattern names the temporary build directories created next to
+// PublicDir, so they share its filesystem and can be renamed into place.
+func (c Config) stagingPattern() string {
+	return "." + filepath.Base(filepath.Clean(c.PublicDir)) + "-staging-*"
+}
+
+// stageOutput creates an empty staging directory for a build and points the
+// renderers at it.
+func (g *Generator) stageOutput() error {
+	parent := filepath.Dir(filepath.Clean(g.cfg.PublicDir))
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		return fmt.Errorf("创建目录 %s: %w", parent, err)
+	}
+	dir, err := os.MkdirTemp(parent, g.cfg.stagingPattern())
+	if err != nil {
+		return fmt.Errorf("创建临时输出目录: %w", err)
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	g.outDir = dir
+	return nil
+}
+
+// discardOutput removes the staging directory of a failed build, leaving
+// PublicDir as it was.
+func (g *Generator) discardOutput() {
+	if g.outDir != "" && g.outDir != g.cfg.PublicDir {
+		os.RemoveAll(g.outDir)
+	}
+	g.outDir = ""
+}
+
+// commitOutput replaces PublicDir with the staging directory. The old output
+// is moved aside first and restored if the second rename fails, so PublicDir
+// always holds a complete build; files of removed posts go away with it.
+func (g *Generator) commitOutput() error {
+	pub := filepath.Clean(g.cfg.PublicDir)
+	old := g.outDir + ".old"
+	hadOld := true
+	if err := os.Rename(pub, old); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("替换 %s: %w", pub, err)
+		}
+		hadOld = false
+	}
+	if err := os.Rename(g.outDir, pub); err != nil {
+		if hadOld {
+			os.Rename(old, pub)
+		}
+		return fmt.Errorf("替换 %s: %w", pub, err)
+	}
+	g.outDir = pub
+	if hadOld {
+		if err := os.RemoveAll(old); err != nil {
+			return fmt.Errorf("删除旧输出 %s: %w", old, err)
+		}
+	}
+	return nil
+}
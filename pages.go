@@ -0,0 +1,80 @@
+package main
+
+import "sort"
+
+// Kinds of content entries, which double as their default layouts.
+const (
+	kindPost = "post"
+	kindPage = "page"
+)
+
+// reservedPageSlugs are top-level names the generator itself writes.
+var reservedPageSlugs = map[string]bool{
+	"index":   true,
+	"links":   true,
+	"archive": true,
+	"posts":   true,
+	"static":  true,
+}
+
+// reservedName reports whether a section name would collide with generated
+// output, including the configured authors directory and the language
+// directories. Entry permalinks are checked path by path in checkRoutes.
+func (g *Generator) reservedName(name string) bool {
+	_, isLang := g.cfg.Languages[name]
+	return reservedPageSlugs[name] || name == g.cfg.AuthorsDir || isLang
+}
+
+// MenuEntry is one navigation link contributed by a page's menu front
+// matter field.
+type MenuEntry struct {
+	Name   string
+	URL    string
+	Weight int
+}
+
+// buildMenu collects the pages that asked for a menu entry, ordered by
+// weight and then by label.
+func buildMenu(pages []Post) []MenuEntry {
+	var menu []MenuEntry
+	for _, p := range pages {
+		if p.Menu == "" {
+			continue
+		}
+		name := p.Menu
+		if name == "true" {
+			name = p.Title
+		}
+		menu = append(menu, MenuEntry{Name: name, URL: p.URL(), Weight: p.Weight})
+	}
+	sort.SliceStable(menu, func(i, j int) bool {
+		if menu[i].Weight != menu[j].Weight {
+			return menu[i].Weight < menu[j].Weight
+		}
+		return menu[i].Name < menu[j].Name
+	})
+	return menu
+}
+
+// checkPageSlugs reports pages whose slug would make cross-references to a
+// post of the same slug ambiguous.
+func (g *Generator) checkPageSlugs(posts, pages []Post) {
+	postSlugs := make(map[string]bool, len(posts))
+	for _, p := range posts {
+		postSlugs[p.Slug] = true
+	}
+	for _, p := range pages {
+		if postSlugs[p.Slug] {
+			g.diags.Errorf(p.File, 0, "页面名 %s 与同名文章冲突", p.Slug)
+		}
+	}
+}
+
+func (g *Generator) renderPages(site *Site) error {
+	for _, page := range site.Pages {
+		if err := g.renderEntry(site, page); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// pagesPermalinkKey selects the permalink pattern of standalone pages in
+// Config.Permalinks; other keys are section names.
+const pagesPermalinkKey = "pages"
+
+// defaultPermalinks keep the historical /posts/<slug>.html layout.
+var defaultPermalinks = map[string]string{
+	pagesPermalinkKey: "/:slug.html",
+	"":                "/:section/:slug.html",
+}
+
+var permalinkToken = regexp.MustCompile(`:[a-z]+`)
+
+// permalinkTokens maps each pattern token to its value for a post.
+var permalinkTokens = map[string]func(Post) string{
+	":section": func(p Post) string { return p.Section },
+	":slug":    func(p Post) string { return p.Slug },
+	":title":   func(p Post) string { return slugify(p.Title) },
+	":year":    func(p Post) string { return p.Time().Format("2006") },
+	":month":   func(p Post) string { return p.Time().Format("01") },
+	":day":     func(p Post) string { return p.Time().Format("02") },
+}
+
+// permalinkPattern returns the pattern for a post: its section's entry in
+// Config.Permalinks, or the default.
+func (c Config) permalinkPattern(p Post) string {
+	key := p.Section
+	if p.Kind == kindPage {
+		key = pagesPermalinkKey
+	}
+	if pattern, ok := c.Permalinks[key]; ok {
+		return pattern
+	}
+	if pattern, ok := defaultPermalinks[key]; ok {
+		return pattern
+	}
+	return defaultPermalinks[""]
+}
+
+// expandPermalink fills in a pattern such as /:year/:month/:slug/. URLs
+// without an extension are clean URLs rendered to index.html, see dirURL.
+func expandPermalink(pattern string, p Post) string {
+	return dirURL(permalinkToken.ReplaceAllStringFunc(pattern, func(tok string) string {
+		if value, ok := permalinkTokens[tok]; ok {
+			return value(p)
+		}
+		return tok
+	}))
+}
+
+// dirURL adds a trailing slash to a site path whose last segment has no
+// extension, so it is written as a directory with an index.html rather
+// than as a file servers would not know the type of.
+func dirURL(url string) string {
+	if !strings.HasSuffix(url, "/") && path.Ext(url) == "" {
+		url += "/"
+	}
+	return url
+}
+
+func validatePermalink(key, pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("permalinks.%s 必须以 / 开头: %q", key, pattern)
+	}
+	for _, tok := range permalinkToken.FindAllString(pattern, -1) {
+		if _, ok := permalinkTokens[tok]; !ok {
+			return fmt.Errorf("permalinks.%s: 未知的占位符 %s", key, tok)
+		}
+	}
+	if !strings.Contains(pattern, ":slug") && !strings.Contains(pattern, ":title") {
+		return fmt.Errorf("permalinks.%s 需要包含 :slug 或 :title: %q", key, pattern)
+	}
+	return nil
+}
+
+// resourceDir is the directory next to a post URL its bundle resources are
+// published to: the URL itself for clean URLs, else the URL without its
+// extension.
+func resourceDir(url string) string {
+	if strings.HasSuffix(url, "/") {
+		return url
+	}
+	return strings.TrimSuffix(url, path.Ext(url)) + "/"
+}
+
+// outputFile is the file below the public tree a site URL is written to.
+func outputFile(url string) string {
+	if strings.HasSuffix(url, "/") {
+		url += "index.html"
+	}
+	return strings.TrimPrefix(url, "/")
+}
+
+// permalinkDateTokens are the tokens that need a post date.
+var permalinkDateTokens = []string{":year", ":month", ":day"}
+
+// usesDate reports whether a pattern has any of permalinkDateTokens.
+func usesDate(pattern string) bool {
+	for _, tok := range permalinkDateTokens {
+		if strings.Contains(pattern, tok) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPermalinks reports entries that would be written to the same URL.
+func (g *Generator) checkPermalinks(posts []Post) {
+	seen := make(map[string]string)
+	for _, p := range posts {
+		file := outputFile(p.URL())
+		if prev, ok := seen[file]; ok {
+			g.diags.Errorf(p.File, 0, "永久链接 %s 与 %s 重复", p.URL(), prev)
+			continue
+		}
+		seen[file] = p.File
+	}
+}
+
+// generatedRoutes maps the files the generator writes for a site besides
+// its entries to a description of each, for checkRoutes.
+func (g *Generator) generatedRoutes(site *Site) map[string]string {
+	routes := make(map[string]string)
+	add := func(url, what string) {
+		routes[outputFile(url)] = what
+	}
+	add(site.LangURL("/"), "首页")
+	add(site.LangURL("/archive.html"), "归档页")
+	add(site.LangURL("/links.html"), "友链页")
+	if site.Lang.Prefix == "" {
+		add("/sitemap.xml", "站点地图")
+	}
+	for _, s := range site.Sections {
+		add(s.URL(), "栏目 "+s.Name+" 的列表页")
+		add(s.FeedURL(), "栏目 "+s.Name+" 的订阅")
+	}
+	if len(site.Series) > 0 {
+		add(site.LangURL("/"+g.cfg.SeriesDir+".html"), "系列索引")
+	}
+	for _, s := range site.Series {
+		add(s.URL(), "系列 "+s.Name+" 的页面")
+	}
+	for _, a := range site.Authors {
+		add(a.URL(), "作者 "+a.ID+" 的页面")
+	}
+	return routes
+}
+
+// checkRoutes reports entries whose permalink would overwrite a generated
+// page or feed of their site, or land among the static files.
+func (g *Generator) checkRoutes(sites []*Site) {
+	for _, site := range sites {
+		routes := g.generatedRoutes(site)
+		var entries []Post
+		for _, s := range site.Sections {
+			entries = append(entries, s.Posts...)
+		}
+		entries = append(entries, site.Pages...)
+		for _, p := range entries {
+			file := outputFile(p.URL())
+			if what, ok := routes[file]; ok {
+				g.diags.Errorf(p.File, 0, "永久链接 %s 会覆盖%s", p.URL(), what)
+			} else if strings.HasPrefix(file, "static/") {
+				g.diags.Errorf(p.File, 0, "永久链接 %s 位于静态文件目录 /static/ 中", p.URL())
+			}
+		}
+	}
+}
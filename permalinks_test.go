@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestExpandPermalink(t *testing.T) {
+	p := Post{Title: "Hello World", Slug: "hello", Section: "notes", Date: "2025-3-7"}
+	tests := []struct {
+		pattern, url, file string
+	}{
+		{"/:section/:slug.html", "/notes/hello.html", "notes/hello.html"},
+		{"/:year/:month/:day/:slug/", "/2025/03/07/hello/", "2025/03/07/hello/index.html"},
+		{"/:title.html", "/hello-world.html", "hello-world.html"},
+		{"/:slug", "/hello/", "hello/index.html"},
+		{"/:year/:month/:slug", "/2025/03/hello/", "2025/03/hello/index.html"},
+	}
+	for _, tt := range tests {
+		url := expandPermalink(tt.pattern, p)
+		if url != tt.url {
+			t.Errorf("expandPermalink(%q) = %q, want %q", tt.pattern, url, tt.url)
+		}
+		if file := outputFile(url); file != tt.file {
+			t.Errorf("outputFile(%q) = %q, want %q", url, file, tt.file)
+		}
+	}
+}
+
+func TestValidatePermalink(t *testing.T) {
+	tests := []struct {
+		pattern string
+		ok      bool
+	}{
+		{"/:section/:slug.html", true},
+		{"/:year/:title/", true},
+		{"posts/:slug.html", false},
+		{"/:year/:month/", false},
+		{"/:category/:slug.html", false},
+	}
+	for _, tt := range tests {
+		if err := validatePermalink("posts", tt.pattern); (err == nil) != tt.ok {
+			t.Errorf("validatePermalink(%q) = %v, want ok %v", tt.pattern, err, tt.ok)
+		}
+	}
+}
+
+func TestUsesDate(t *testing.T) {
+	tests := map[string]bool{
+		"/:section/:slug.html": false,
+		"/:year/:slug/":        true,
+		"/archive/:day-:slug":  true,
+	}
+	for pattern, want := range tests {
+		if got := usesDate(pattern); got != want {
+			t.Errorf("usesDate(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
+
+func TestResourceDir(t *testing.T) {
+	tests := map[string]string{
+		"/posts/hello.html":  "/posts/hello/",
+		"/2025/09/hello/":    "/2025/09/hello/",
+		"/en/notes/db.html":  "/en/notes/db/",
+		"/notes/go1.22.html": "/notes/go1.22/",
+	}
+	for url, want := range tests {
+		if got := resourceDir(url); got != want {
+			t.Errorf("resourceDir(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Redirect maps an old site path to where its content lives now.
+type Redirect struct {
+	From string `toml:"from"`
+	To   string `toml:"to"`
+
+	// File and Line locate the alias or redirects file entry for diagnostics.
+	File string `toml:"-"`
+	Line int    `toml:"-"`
+}
+
+// loadRedirects reads the site-wide redirects file, if there is one.
+func loadRedirects(file string) ([]Redirect, error) {
+	data, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var redirectsConfig struct {
+		Redirects []Redirect `toml:"redirects"`
+	}
+	if _, err := toml.Decode(string(data), &redirectsConfig); err != nil {
+		return nil, fmt.Errorf("解析 %s: %w", file, err)
+	}
+
+	lines := tableLines(data, "redirects")
+	redirects := redirectsConfig.Redirects
+	for i := range redirects {
+		redirects[i].File = file
+		if len(lines) == len(redirects) {
+			redirects[i].Line = lines[i]
+		}
+	}
+	return redirects, nil
+}
+
+// collectRedirects combines the aliases of every post and page in every
+// language with the redirects file.
+func (g *Generator) collectRedirects(sites []*Site) ([]Redirect, error) {
+	var entries []Post
+	for _, site := range sites {
+		for _, section := range site.Sections {
+			entries = append(entries, section.Posts...)
+		}
+		entries = append(entries, site.Pages...)
+	}
+
+	var redirects []Redirect
+	for _, p := range entries {
+		for _, alias := range p.Aliases {
+			redirects = append(redirects, Redirect{From: alias, To: p.URL(), File: p.File, Line: p.aliasesLine})
+		}
+	}
+	fromFile, err := loadRedirects(g.cfg.RedirectsFile)
+	if err != nil {
+		return nil, err
+	}
+	return append(redirects, fromFile...), nil
+}
+
+// redirectFile is where the stub for an old path goes; paths without an
+// extension are treated as directories.
+func redirectFile(from string) string {
+	return outputFile(dirURL(from))
+}
+
+// targetLanguage is the language of the site a redirect target lives in:
+// the one whose directory the path is under, else the default language.
+func targetLanguage(sites []*Site, to string) *Language {
+	for _, site := range sites[1:] {
+		prefix := site.Lang.Prefix
+		if to == prefix || strings.HasPrefix(to, prefix+"/") {
+			return site.Lang
+		}
+	}
+	return sites[0].Lang
+}
+
+// renderRedirects writes a stub per redirect from the redirect template: an
+// immediate meta refresh for browsers and a canonical link for crawlers, in
+// the language of the target. It runs after everything else so a redirect
+// that would overwrite a generated file is reported instead.
+func (g *Generator) renderRedirects(sites []*Site) error {
+	redirects, err := g.collectRedirects(sites)
+	if err != nil {
+		return fmt.Errorf("读取重定向: %w", err)
+	}
+	if len(redirects) == 0 {
+		return nil
+	}
+	tmpl, err := g.templates.Page("redirect")
+	if err != nil {
+		return err
+	}
+	written := make(map[string]Redirect)
+	for _, r := range redirects {
+		if !strings.HasPrefix(r.From, "/") || strings.Contains(r.From, "..") {
+			g.diags.Errorf(r.File, r.Line, "重定向来源必须是以 / 开头的站内路径: %q", r.From)
+			continue
+		}
+		if r.To == "" {
+			g.diags.Errorf(r.File, r.Line, "重定向 %s 缺少目标 (to)", r.From)
+			continue
+		}
+		rel := redirectFile(r.From)
+		if prev, ok := written[rel]; ok {
+			g.diags.Errorf(r.File, r.Line, "重定向 %s 与 %s 中的重定向重复", r.From, prev.File)
+			continue
+		}
+		outPath := filepath.Join(g.outDir, filepath.FromSlash(rel))
+		if _, err := os.Stat(outPath); err == nil {
+			g.diags.Errorf(r.File, r.Line, "重定向 %s 会覆盖已生成的 %s", r.From, rel)
+			continue
+		}
+		written[rel] = r
+
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return err
+		}
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("创建 %s: %w", outPath, err)
+		}
+		ctx := map[string]interface{}{
+			"To":        g.relURL(r.To),
+			"Canonical": g.absURL(r.To),
+			"Lang":      targetLanguage(sites, r.To),
+		}
+		if err := tmpl.Execute(f, ctx); err != nil {
+			f.Close()
+			return fmt.Errorf("渲染重定向 %s: %w", r.From, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("关闭 %s: %w", outPath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// postsSection holds the posts directly in ContentDir; they make up the home
+// page, the archive and the site feed.
+const postsSection = "posts"
+
+// sectionIndex optionally sets a section's title in its front matter;
+// _index.<code>.md does the same for another language.
+const sectionIndex = "_index.md"
+
+// Section is one listing of posts: postsSection or a subdirectory of
+// ContentDir that is not a page bundle.
+type Section struct {
+	Name  string
+	Title string
+	Posts []Post
+
+	prefix string            // language directory, see Language.Prefix
+	titles map[string]string // per language code, from the _index files
+}
+
+// URL is the section's list page.
+func (s Section) URL() string {
+	return s.prefix + "/" + s.Name + "/"
+}
+
+// FeedURL is the section's RSS feed; the posts section feeds the site.
+func (s Section) FeedURL() string {
+	if s.Name == postsSection {
+		return s.prefix + "/feed.xml"
+	}
+	return s.prefix + "/" + s.Name + "/feed.xml"
+}
+
+// inLanguage narrows the section to the posts of one language.
+func (s Section) inLanguage(lang *Language, defaultLang string) Section {
+	out := Section{Name: s.Name, prefix: lang.Prefix}
+	for _, p := range s.Posts {
+		if p.Lang == lang.Code {
+			out.Posts = append(out.Posts, p)
+		}
+	}
+	switch {
+	case s.Name == postsSection:
+		out.Title = lang.T("posts")
+	case s.titles[lang.Code] != "":
+		out.Title = s.titles[lang.Code]
+	case s.titles[defaultLang] != "":
+		out.Title = s.titles[defaultLang]
+	default:
+		out.Title = s.Name
+	}
+	return out
+}
+
+// loadSections loads postsSection from ContentDir and one more section per
+// subdirectory without an index.md, each sorted newest first.
+func (g *Generator) loadSections() ([]Section, error) {
+	posts, err := g.loadContent(g.cfg.ContentDir, kindPost, postsSection)
+	if err != nil {
+		return nil, err
+	}
+	sections := []Section{{Name: postsSection, Posts: posts}}
+
+	entries, err := os.ReadDir(g.cfg.ContentDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		dir := filepath.Join(g.cfg.ContentDir, e.Name())
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if len(g.cfg.bundleIndexes(dir)) > 0 {
+			continue
+		}
+		if g.reservedName(e.Name()) {
+			g.diags.Errorf(dir, 0, "栏目名 %s 与生成的页面冲突", e.Name())
+			continue
+		}
+		section := Section{Name: e.Name(), titles: make(map[string]string)}
+		for _, code := range g.cfg.languageCodes() {
+			name := sectionIndex
+			if code != g.cfg.DefaultLanguage {
+				name = strings.TrimSuffix(sectionIndex, ".md") + "." + code + ".md"
+			}
+			if content, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				section.titles[code] = parseFrontMatter(content).fields["title"]
+			}
+		}
+		if section.Posts, err = g.loadContent(dir, kindPost, section.Name); err != nil {
+			return nil, fmt.Errorf("栏目 %s: %w", section.Name, err)
+		}
+		sections = append(sections, section)
+	}
+
+	for _, s := range sections {
+		sort.Slice(s.Posts, func(i, j int) bool {
+			return s.Posts[i].Time().After(s.Posts[j].Time())
+		})
+	}
+	return sections, nil
+}
+
+// renderSections writes each section's list page and feed.
+func (g *Generator) renderSections(site *Site) error {
+	for _, section := range site.Sections {
+		tmpl, err := g.templates.Page(section.Name+"/list", "list")
+		if err != nil {
+			return err
+		}
+		dir := filepath.Join(g.siteDir(site), section.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		outPath := filepath.Join(dir, "index.html")
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("创建 %s: %w", outPath, err)
+		}
+		ctx := map[string]interface{}{
+			"Site":    site,
+			"Title":   section.Title,
+			"Section": section,
+			"Posts":   section.Posts,
+		}
+		if err := tmpl.Execute(f, ctx); err != nil {
+			f.Close()
+			return fmt.Errorf("渲染栏目 %s: %w", section.Name, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("关闭 %s: %w", outPath, err)
+		}
+
+		title := site.Title
+		if section.Name != postsSection {
+			title += " - " + section.Title
+		}
+		if err := g.writeFeed(site, filepath.Join(g.outDir, filepath.FromSlash(section.FeedURL())), title, section.URL(), section.Posts); err != nil {
+			return fmt.Errorf("生成 %s 订阅: %w", section.Name, err)
+		}
+	}
+	return nil
+}
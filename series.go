@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Series is a named run of posts, ordered by their part number and then by
+// date, with an overview page under SeriesDir.
+type Series struct {
+	Name  string
+	Slug  string
+	Posts []Post
+
+	dir string
+}
+
+// URL is the series overview page.
+func (s Series) URL() string {
+	return "/" + s.dir + "/" + s.Slug + ".html"
+}
+
+// buildSeries groups the posts of every section by their series field,
+// sorted by series name.
+func buildSeries(sections []Section, dir string) []Series {
+	var series []Series
+	index := make(map[string]int)
+	for _, section := range sections {
+		for _, p := range section.Posts {
+			if p.Series == "" {
+				continue
+			}
+			i, ok := index[p.Series]
+			if !ok {
+				i = len(series)
+				index[p.Series] = i
+				series = append(series, Series{Name: p.Series, Slug: slugify(p.Series), dir: dir})
+			}
+			series[i].Posts = append(series[i].Posts, p)
+		}
+	}
+	for _, s := range series {
+		sort.SliceStable(s.Posts, func(i, j int) bool {
+			a, b := s.Posts[i], s.Posts[j]
+			if a.Part != b.Part {
+				return a.Part < b.Part
+			}
+			return a.Time().Before(b.Time())
+		})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].Name < series[j].Name })
+	return series
+}
+
+// seriesOf returns the series post belongs to, or nil.
+func (s *Site) seriesOf(post Post) *Series {
+	if post.Series == "" {
+		return nil
+	}
+	for i := range s.Series {
+		if s.Series[i].Name == post.Series {
+			return &s.Series[i]
+		}
+	}
+	return nil
+}
+
+// renderSeries writes an overview page per series under SeriesDir plus an
+// index of all series next to it, SeriesDir.html, which leaves the
+// directory's index to a section of the same name.
+func (g *Generator) renderSeries(site *Site) error {
+	if len(site.Series) == 0 {
+		return nil
+	}
+	dir := filepath.Join(g.siteDir(site), g.cfg.SeriesDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmpl, err := g.templates.Page("series")
+	if err != nil {
+		return err
+	}
+	for _, series := range site.Series {
+		ctx := map[string]interface{}{
+			"Site":   site,
+			"Title":  series.Name,
+			"Series": series,
+		}
+		outPath := filepath.Join(dir, series.Slug+".html")
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("创建 %s: %w", outPath, err)
+		}
+		if err := tmpl.Execute(f, ctx); err != nil {
+			f.Close()
+			return fmt.Errorf("渲染系列 %s: %w", series.Name, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("关闭 %s: %w", outPath, err)
+		}
+	}
+
+	list, err := g.templates.Page("series-list")
+	if err != nil {
+		return err
+	}
+	ctx := map[string]interface{}{
+		"Site":   site,
+		"Title":  site.Lang.T("series"),
+		"Series": site.Series,
+	}
+	outPath := filepath.Join(g.siteDir(site), g.cfg.SeriesDir+".html")
+	f, err := os.Create(outPath)
+	if err != nil {
+		return fmt.Errorf("创建 %s: %w", outPath, err)
+	}
+	defer f.Close()
+	if err := list.Execute(f, ctx); err != nil {
+		return fmt.Errorf("渲染系列列表: %w", err)
+	}
+	return nil
+}
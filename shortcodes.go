@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/yuin/goldmark/parser"
+)
+
+// shortcodeRe matches {{< name args >}} and the closing {{< /name >}}.
+var shortcodeRe = regexp.MustCompile(`\{\{<\s*(/?)([\w-]+)((?:\s+[^>]*?)?)\s*>\}\}`)
+
+// shortcodeArgRe splits shortcode arguments into key=value pairs and
+// positional values, either of which may be double-quoted.
+var shortcodeArgRe = regexp.MustCompile(`(?:([\w-]+)=)?(?:"((?:[^"\\]|\\.)*)"|(\S+))`)
+
+// Shortcode is the data a shortcode template executes against.
+type Shortcode struct {
+	Name   string
+	Params map[string]string
+	Args   []string
+	// Inner is the rendered markdown between an opening and closing tag.
+	Inner template.HTML
+	Post  PostRef
+
+	// bundle is the public directory of the post's bundle resources, or
+	// "" for a post that is not a bundle.
+	bundle string
+}
+
+// Resolve resolves a destination the way links in the post's markdown
+// are, so relative paths in a bundle point at its resources:
+// {{.Resolve (.Get "src")}}.
+func (s Shortcode) Resolve(dest string) string {
+	if s.bundle == "" {
+		return dest
+	}
+	return string(resolveBundleURL(s.bundle, []byte(dest)))
+}
+
+// Get returns a named parameter for a string key or a positional argument
+// for an int key, and "" when absent.
+func (s Shortcode) Get(key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		return s.Params[k]
+	case int:
+		if k >= 0 && k < len(s.Args) {
+			return s.Args[k]
+		}
+	}
+	return ""
+}
+
+// loadShortcodes parses every shortcodes/*.html from the template layers,
+// each usable under its file name without extension.
+func (g *Generator) loadShortcodes() error {
+	files, err := fs.Glob(g.templatesFS, "shortcodes/*.html")
+	if err != nil {
+		return err
+	}
+	g.shortcodes = template.New("shortcodes").Funcs(g.blogTemplateFuncs())
+	for _, file := range files {
+		content, err := fs.ReadFile(g.templatesFS, file)
+		if err != nil {
+			return fmt.Errorf("读取短代码 %s: %w", file, err)
+		}
+		name := strings.TrimSuffix(path.Base(file), ".html")
+		if _, err := g.shortcodes.New(name).Parse(string(content)); err != nil {
+			return fmt.Errorf("解析短代码 %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// shortcodeExpander replaces shortcodes in one post's markdown with
+// placeholders and keeps the rendered HTML to substitute after conversion,
+// so goldmark never sees (and never strips) the raw HTML.
+type shortcodeExpander struct {
+	g    *Generator
+	post *Post
+	// newContext builds the goldmark parser context for each conversion,
+	// since inner shortcode content is converted separately.
+	newContext   func() parser.Context
+	placeholders []string
+}
+
+func (e *shortcodeExpander) placeholder(html string) string {
+	token := fmt.Sprintf("SHORTCODEPLACEHOLDER%04d", len(e.placeholders))
+	e.placeholders = append(e.placeholders, html)
+	return token
+}
+
+// substitute puts rendered shortcodes back, unwrapping the paragraph
+// goldmark adds around a shortcode that sits on its own line.
+func (e *shortcodeExpander) substitute(html string) string {
+	for i := len(e.placeholders) - 1; i >= 0; i-- {
+		token := fmt.Sprintf("SHORTCODEPLACEHOLDER%04d", i)
+		html = strings.ReplaceAll(html, "<p>"+token+"</p>", e.placeholders[i])
+		html = strings.ReplaceAll(html, token, e.placeholders[i])
+	}
+	return html
+}
+
+// render converts markdown containing shortcodes to HTML. line is the file
+// line src starts on, used in error messages.
+func (e *shortcodeExpander) render(src string, line int) (string, error) {
+	expanded, err := e.expand(src, line)
+	if err != nil {
+		return "", err
+	}
+	html := convertMarkdownToHTML(e.g.md, expanded, parser.WithContext(e.newContext()))
+	return e.substitute(html), nil
+}
+
+func (e *shortcodeExpander) expand(src string, line int) (string, error) {
+	code := codeRanges(src)
+	var matches [][]int
+	for _, m := range shortcodeRe.FindAllStringSubmatchIndex(src, -1) {
+		if !inRanges(code, m[0]) {
+			matches = append(matches, m)
+		}
+	}
+
+	var out strings.Builder
+	last := 0
+	for i := 0; i < len(matches); i++ {
+		m := matches[i]
+		at := line + strings.Count(src[:m[0]], "\n")
+		closing, name, args := src[m[2]:m[3]] == "/", src[m[4]:m[5]], src[m[6]:m[7]]
+		if closing {
+			return "", e.errorf(at, "多余的结束短代码 {{< /%s >}}", name)
+		}
+
+		sc := Shortcode{Name: name, Post: PostRef{Title: e.post.Title, URL: e.post.URL()}}
+		if e.post.BundleDir != "" {
+			sc.bundle = e.post.ResourceURL()
+		}
+		sc.Params, sc.Args = parseShortcodeArgs(args)
+
+		end := m[1]
+		if j := matchingClose(src, matches, i, name); j > 0 {
+			inner, err := e.render(src[m[1]:matches[j][0]], line+strings.Count(src[:m[1]], "\n"))
+			if err != nil {
+				return "", err
+			}
+			sc.Inner = template.HTML(inner)
+			end = matches[j][1]
+			i = j
+		}
+
+		tmpl := e.g.shortcodes.Lookup(name)
+		if tmpl == nil {
+			return "", e.errorf(at, "未知的短代码 %q", name)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, sc); err != nil {
+			return "", e.errorf(at, "渲染短代码 %q: %v", name, err)
+		}
+
+		out.WriteString(src[last:m[0]])
+		out.WriteString(e.placeholder(strings.TrimSpace(buf.String())))
+		last = end
+	}
+	out.WriteString(src[last:])
+	return out.String(), nil
+}
+
+func (e *shortcodeExpander) errorf(line int, format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", e.post.File, line, fmt.Sprintf(format, args...))
+}
+
+// matchingClose returns the index of the {{< /name >}} closing matches[open],
+// honouring nesting of the same shortcode, or -1 for a self-closing use.
+func matchingClose(src string, matches [][]int, open int, name string) int {
+	depth := 0
+	for j := open + 1; j < len(matches); j++ {
+		m := matches[j]
+		if src[m[4]:m[5]] != name {
+			continue
+		}
+		if src[m[2]:m[3]] != "/" {
+			depth++
+			continue
+		}
+		if depth == 0 {
+			return j
+		}
+		depth--
+	}
+	return -1
+}
+
+// stripShortcodes removes shortcode tags from src, keeping the content
+// between paired tags, for plain-text uses such as summaries.
+func stripShortcodes(src string) string {
+	return shortcodeRe.ReplaceAllString(src, "")
+}
+
+func parseShortcodeArgs(args string) (map[string]string, []string) {
+	params := make(map[string]string)
+	var positional []string
+	for _, m := range shortcodeArgRe.FindAllStringSubmatch(args, -1) {
+		value := m[3]
+		if m[3] == "" {
+			if unquoted, err := strconv.Unquote(`"` + m[2] + `"`); err == nil {
+				value = unquoted
+			} else {
+				value = m[2]
+			}
+		}
+		if m[1] != "" {
+			params[m[1]] = value
+		} else {
+			positional = append(positional, value)
+		}
+	}
+	return params, positional
+}
+
+// codeRanges returns the byte ranges of fenced code blocks, fenced with
+// ``` or ~~~, and of inline code spans in src, so shortcode syntax inside
+// code samples is left alone.
+func codeRanges(src string) [][2]int {
+	var ranges [][2]int
+	var fence string
+	start, offset, text := -1, 0, 0
+	for _, l := range strings.SplitAfter(src, "\n") {
+		marker := fenceMarker(strings.TrimSpace(l))
+		switch {
+		case start < 0 && marker != "":
+			ranges = append(ranges, codeSpans(src, text, offset)...)
+			start, fence = offset, marker
+		case start >= 0 && marker != "" && marker[0] == fence[0] && len(marker) >= len(fence) &&
+			strings.Trim(strings.TrimSpace(l), marker[:1]) == "":
+			ranges = append(ranges, [2]int{start, offset + len(l)})
+			start, text = -1, offset+len(l)
+		}
+		offset += len(l)
+	}
+	if start >= 0 {
+		return append(ranges, [2]int{start, len(src)})
+	}
+	return append(ranges, codeSpans(src, text, len(src))...)
+}
+
+// fenceMarker returns the run of three or more backticks or tildes a code
+// fence line starts with, or "".
+func fenceMarker(line string) string {
+	for _, c := range []string{"`", "~"} {
+		if n := len(line) - len(strings.TrimLeft(line, c)); n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// codeSpans returns the inline code spans in src[from:to]: a run of
+// backticks through the next run of the same length. A run without a
+// match is literal text.
+func codeSpans(src string, from, to int) [][2]int {
+	run := func(i int) int {
+		n := 0
+		for i+n < to && src[i+n] == '`' {
+			n++
+		}
+		return n
+	}
+	var ranges [][2]int
+	for i := from; i < to; {
+		if src[i] != '`' {
+			i++
+			continue
+		}
+		n := run(i)
+		end := -1
+		for j := i + n; j < to; {
+			if src[j] != '`' {
+				j++
+				continue
+			}
+			m := run(j)
+			if m == n {
+				end = j + m
+				break
+			}
+			j += m
+		}
+		if end < 0 {
+			i += n
+			continue
+		}
+		ranges = append(ranges, [2]int{i, end})
+		i = end
+	}
+	return ranges
+}
+
+func inRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}
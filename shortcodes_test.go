@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// codeTexts returns the text of each range codeRanges finds in src.
+func codeTexts(src string) []string {
+	var texts []string
+	for _, r := range codeRanges(src) {
+		texts = append(texts, src[r[0]:r[1]])
+	}
+	return texts
+}
+
+func TestCodeRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"no code", "plain {{< x >}}\n", nil},
+		{"backtick fence", "a\n```go\n{{< x >}}\n```\nb\n", []string{"```go\n{{< x >}}\n```\n"}},
+		{"tilde fence", "~~~\n{{< x >}}\n~~~\n", []string{"~~~\n{{< x >}}\n~~~\n"}},
+		{"longer closing fence", "````\nx\n`````\nafter\n", []string{"````\nx\n`````\n"}},
+		{"shorter fence does not close", "````\nx\n```\ny\n````\n", []string{"````\nx\n```\ny\n````\n"}},
+		{"other fence char does not close", "~~~\nx\n```\ny\n~~~\n", []string{"~~~\nx\n```\ny\n~~~\n"}},
+		{"closing fence with info string does not close", "```\nx\n```go\ny\n```\n", []string{"```\nx\n```go\ny\n```\n"}},
+		{"unclosed fence runs to the end", "a\n```\n{{< x >}}\n", []string{"```\n{{< x >}}\n"}},
+		{"inline span", "use `{{< x >}}` here", []string{"`{{< x >}}`"}},
+		{"double backtick span", "``a ` b`` and `c`", []string{"``a ` b``", "`c`"}},
+		{"unmatched backticks", "a ``b` c", nil},
+		{"spans around a fence", "`a`\n```\nb\n```\n`c`\n", []string{"`a`", "```\nb\n```\n", "`c`"}},
+	}
+	for _, tt := range tests {
+		if got := codeTexts(tt.src); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: codeRanges = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCodeSpans(t *testing.T) {
+	src := "`a` b `c` d"
+	if got, want := codeSpans(src, 0, len(src)), [][2]int{{0, 3}, {6, 9}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("codeSpans = %v, want %v", got, want)
+	}
+	// Limited to src[4:], the first span is out of reach.
+	if got, want := codeSpans(src, 4, len(src)), [][2]int{{6, 9}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("codeSpans from 4 = %v, want %v", got, want)
+	}
+}
+
+func TestMatchingClose(t *testing.T) {
+	tests := []struct {
+		src  string
+		open int
+		want int
+	}{
+		{`{{< figure src="a.png" >}}`, 0, -1},
+		{`{{< callout >}}x{{< /callout >}}`, 0, 1},
+		{`{{< callout >}}{{< callout >}}x{{< /callout >}}{{< /callout >}}`, 0, 3},
+		{`{{< callout >}}{{< callout >}}x{{< /callout >}}{{< /callout >}}`, 1, 2},
+		{`{{< callout >}}{{< figure >}}{{< /callout >}}`, 0, 2},
+		{`{{< callout >}}{{< /note >}}`, 0, -1},
+	}
+	for _, tt := range tests {
+		matches := shortcodeRe.FindAllStringSubmatchIndex(tt.src, -1)
+		if got := matchingClose(tt.src, matches, tt.open, "callout"); got != tt.want {
+			t.Errorf("matchingClose(%q, %d) = %d, want %d", tt.src, tt.open, got, tt.want)
+		}
+	}
+}
+
+func TestShortcodeResolve(t *testing.T) {
+	tests := []struct {
+		bundle, dest, want string
+	}{
+		{"", "pic.png", "pic.png"},
+		{"/posts/hello/", "pic.png", "/posts/hello/pic.png"},
+		{"/posts/hello/", "img/pic.png", "/posts/hello/img/pic.png"},
+		{"/posts/hello/", "/static/pic.png", "/static/pic.png"},
+		{"/posts/hello/", "https://example.com/pic.png", "https://example.com/pic.png"},
+	}
+	for _, tt := range tests {
+		if got := (Shortcode{bundle: tt.bundle}).Resolve(tt.dest); got != tt.want {
+			t.Errorf("Resolve(%q) in %q = %q, want %q", tt.dest, tt.bundle, got, tt.want)
+		}
+	}
+}
+
+func TestSummaryStripsMarkup(t *testing.T) {
+	body := "{{< figure src=\"a.png\" >}}\n\n见 [[redis]] 和 [[notes/db#索引|数据库笔记]]。\n{{< callout note >}}提示{{< /callout >}}\n"
+	got := extractSummary(stripWikiLinks(stripShortcodes(body)))
+	if strings.Contains(got, "{{<") || strings.Contains(got, "[[") {
+		t.Errorf("summary still has markup: %q", got)
+	}
+	for _, want := range []string{"见 redis 和 数据库笔记。", "提示"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary %q lacks %q", got, want)
+		}
+	}
+}
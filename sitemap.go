@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sitemapURLSet is a sitemaps.org sitemap.
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	Lastmod string `xml:"lastmod,omitempty"`
+}
+
+// renderSitemap writes sitemap.xml at the site root, listing the pages of
+// every language. Listing pages are as new as their newest post.
+func (g *Generator) renderSitemap(sites []*Site) error {
+	urlset := sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	add := func(url string, lastmod time.Time) {
+		u := sitemapURL{Loc: g.absURL(url)}
+		if !lastmod.IsZero() {
+			u.Lastmod = lastmod.Format(time.RFC3339)
+		}
+		urlset.URLs = append(urlset.URLs, u)
+	}
+
+	for _, site := range sites {
+		var all []Post
+		for _, section := range site.Sections {
+			all = append(all, section.Posts...)
+		}
+		add(site.LangURL("/"), newestLastmod(site.Posts))
+		for _, section := range site.Sections {
+			add(section.URL(), newestLastmod(section.Posts))
+			for _, p := range section.Posts {
+				add(p.URL(), p.Lastmod)
+			}
+		}
+		for _, p := range site.Pages {
+			add(p.URL(), p.Lastmod)
+		}
+		if len(site.Series) > 0 {
+			add(site.LangURL("/"+g.cfg.SeriesDir+".html"), time.Time{})
+		}
+		for _, series := range site.Series {
+			add(series.URL(), newestLastmod(series.Posts))
+		}
+		for _, author := range site.Authors {
+			add(author.URL(), newestLastmod(author.Posts))
+		}
+		add(site.LangURL("/archive.html"), newestLastmod(all))
+		add(site.LangURL("/links.html"), time.Time{})
+	}
+
+	out, err := xml.MarshalIndent(urlset, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(g.outDir, "sitemap.xml"), append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
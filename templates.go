@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"strings"
+)
+
+// layoutFile is the base layout every page fragment is combined with.
+const layoutFile = "main.html"
+
+// standalonePages are complete documents rather than fragments of the
+// layout; they can still use the partials.
+var standalonePages = map[string]bool{"redirect.html": true}
+
+// templateSet holds the layered template directories parsed once: the
+// layout plus partials as a base, and one clone of it per page fragment
+// (index.html, post.html, ...), so fragments can override the layout's named
+// blocks independently.
+type templateSet struct {
+	pages map[string]*template.Template
+}
+
+// loadTemplates parses main.html, partials/*.html and every other *.html
+// fragment from the template layers, including section-specific ones such as
+// notes/post.html, plus the standalonePages. Partials are available to all
+// templates as {{template "partials/<name>" .}}.
+func (g *Generator) loadTemplates() (*templateSet, error) {
+	fsys := g.templatesFS
+	mainContent, err := fs.ReadFile(fsys, layoutFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取主模板 %s: %w", layoutFile, err)
+	}
+	base, err := template.New("blog").Funcs(g.blogTemplateFuncs()).Parse(string(mainContent))
+	if err != nil {
+		return nil, fmt.Errorf("解析主模板 %s: %w", layoutFile, err)
+	}
+
+	partials, err := fs.Glob(fsys, "partials/*.html")
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range partials {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("读取片段 %s: %w", path, err)
+		}
+		name := strings.TrimSuffix(path, ".html")
+		if _, err := base.New(name).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("解析片段 %s: %w", path, err)
+		}
+	}
+
+	var fragments []string
+	err = fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (path == "partials" || path == "shortcodes") {
+			return fs.SkipDir
+		}
+		if !d.IsDir() && path != layoutFile && strings.HasSuffix(path, ".html") {
+			fragments = append(fragments, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	set := &templateSet{pages: make(map[string]*template.Template)}
+	for _, path := range fragments {
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return nil, fmt.Errorf("读取片段 %s: %w", path, err)
+		}
+		page, err := base.Clone()
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(path, ".html")
+		if standalonePages[path] {
+			page = page.New(name)
+		}
+		if _, err := page.Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("解析片段 %s: %w", path, err)
+		}
+		set.pages[name] = page
+	}
+	return set, nil
+}
+
+// Page returns the layout combined with the named fragment, e.g. "post",
+// or with the first of fallbacks that exists.
+func (s *templateSet) Page(name string, fallbacks ...string) (*template.Template, error) {
+	for _, n := range append([]string{name}, fallbacks...) {
+		if tmpl, ok := s.pages[n]; ok {
+			return tmpl, nil
+		}
+	}
+	return nil, fmt.Errorf("模板 %s.html 不存在", name)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// builtinThemeName is the theme compiled into the binary from
+// themes/default, used as the last fallback layer for every site.
+const builtinThemeName = "default"
+
+//go:embed themes/default
+var builtinTheme embed.FS
+
+// layeredFS resolves each name from the first layer that has it, and merges
+// directory listings across layers, so a site can override single files of
+// a theme without copying the rest.
+type layeredFS []fs.FS
+
+func (l layeredFS) Open(name string) (fs.File, error) {
+	for _, layer := range l {
+		f, err := layer.Open(name)
+		if err == nil {
+			return f, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (l layeredFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	found := false
+	for _, layer := range l {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		found = true
+		for _, e := range layerEntries {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// themeDir is the on-disk directory of the configured theme.
+func (c Config) themeDir() string {
+	return filepath.Join(c.ThemesDir, c.Theme)
+}
+
+func (c Config) validateTheme() error {
+	if c.Theme == builtinThemeName {
+		return nil
+	}
+	if _, err := os.Stat(c.themeDir()); err != nil {
+		return fmt.Errorf("主题 %s 不存在: %w", c.Theme, err)
+	}
+	return nil
+}
+
+// themeLayers stacks, for one of "templates" or "static", the site-level
+// directory over the configured theme over the built-in theme.
+func themeLayers(cfg Config, kind, siteDir string) fs.FS {
+	builtin, err := fs.Sub(builtinTheme, path.Join("themes", builtinThemeName, kind))
+	if err != nil {
+		panic(err) // the embed pattern guarantees the directory exists
+	}
+	return layeredFS{
+		os.DirFS(siteDir),
+		os.DirFS(filepath.Join(cfg.themeDir(), kind)),
+		builtin,
+	}
+}
+
+// copyFS writes every file of fsys below dst, overwriting existing files.
+func copyFS(fsys fs.FS, dst string) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		data, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+}
+
+// initCommand implements `init [-force] [file...]`, ejecting the built-in
+// theme into TemplatesDir, StaticDir and I18nDir for customization. Files
+// are named like templates/post.html, static/style.css or i18n/en.toml; with
+// none given everything is ejected. Existing files are kept unless -force is set.
+func initCommand(cfg Config, args []string) error {
+	fset := flag.NewFlagSet("init", flag.ContinueOnError)
+	force := fset.Bool("force", false, "覆盖已存在的文件")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	wanted := fset.Args()
+
+	root := path.Join("themes", builtinThemeName)
+	targets := map[string]string{
+		"templates": cfg.TemplatesDir,
+		"static":    cfg.StaticDir,
+		"i18n":      cfg.I18nDir,
+	}
+	matched := 0
+	err := fs.WalkDir(builtinTheme, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel := strings.TrimPrefix(p, root+"/")
+		if len(wanted) > 0 && !slices.Contains(wanted, rel) {
+			return nil
+		}
+		matched++
+		kind, name, _ := strings.Cut(rel, "/")
+		dst := filepath.Join(targets[kind], filepath.FromSlash(name))
+		if _, err := os.Stat(dst); err == nil && !*force {
+			fmt.Printf("跳过 %s (已存在)\n", dst)
+			return nil
+		}
+		data, err := builtinTheme.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, data, 0644); err != nil {
+			return fmt.Errorf("写入 %s: %w", dst, err)
+		}
+		fmt.Printf("写入 %s\n", dst)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if matched < len(wanted) {
+		return fmt.Errorf("内置主题中没有 %s", strings.Join(wanted, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+// xrefScheme prefixes link destinations that point at another post by slug,
+// as in [text](post:slug#anchor). [[slug]] is shorthand for the same link
+// with the target post's title as text. Entries of other sections are
+// addressed as section/slug, or by bare slug when only one section has it;
+// a bare slug shared by several sections is an error where it is used.
+// Translations may link to posts that only exist in the default language.
+const xrefScheme = "post:"
+
+var (
+	// xrefPostsKey holds the map[string]PostRef of every loaded post.
+	xrefPostsKey = parser.NewContextKey()
+	// xrefAmbiguousKey holds the map[string][]string of bare slugs shared
+	// by several sections to the section/slug keys they could mean.
+	xrefAmbiguousKey = parser.NewContextKey()
+	// xrefResultKey collects what the transformer resolved for one post.
+	xrefResultKey = parser.NewContextKey()
+)
+
+// PostRef is the minimal view of a post needed to link to it.
+type PostRef struct {
+	Title string
+	URL   string
+}
+
+// xrefResult records the URLs a post links to and any slugs it could not
+// resolve, either because no post has them or because several do.
+type xrefResult struct {
+	targets   []string
+	unknown   []string
+	ambiguous []string
+}
+
+// wikiLinkRe matches [[slug]], [[slug#anchor]] and [[slug|text]].
+var wikiLinkRe = regexp.MustCompile(`\[\[\s*([^\s\[\]|#]+)(?:#[^\s\[\]|]*)?\s*(?:\|([^\]]*))?\]\]`)
+
+// stripWikiLinks replaces [[slug]] links in src with their text, or the
+// slug when they have none, for plain-text uses such as summaries.
+func stripWikiLinks(src string) string {
+	return wikiLinkRe.ReplaceAllStringFunc(src, func(m string) string {
+		sub := wikiLinkRe.FindStringSubmatch(m)
+		if label := strings.TrimSpace(sub[2]); label != "" {
+			return label
+		}
+		return sub[1]
+	})
+}
+
+// wikiLinkParser parses [[slug]], [[slug#anchor]] and [[slug|text]] into a
+// post: link. Anything else starting with '[' is left to the link parser.
+type wikiLinkParser struct{}
+
+func (wikiLinkParser) Trigger() []byte {
+	return []byte{'['}
+}
+
+func (wikiLinkParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("[[")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte("]]"))
+	if end < 0 {
+		return nil
+	}
+	inner := strings.TrimSpace(string(line[2:end]))
+	target, label, _ := strings.Cut(inner, "|")
+	target = strings.TrimSpace(target)
+	if target == "" || strings.ContainsAny(target, " []") {
+		return nil
+	}
+	block.Advance(end + 2)
+
+	link := ast.NewLink()
+	link.Destination = []byte(xrefScheme + target)
+	if label = strings.TrimSpace(label); label != "" {
+		link.AppendChild(link, ast.NewString([]byte(label)))
+	}
+	return link
+}
+
+// xrefTransformer resolves post: destinations against the loaded posts,
+// filling in the target title when a link has no text of its own.
+type xrefTransformer struct{}
+
+func (xrefTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	posts, _ := pc.Get(xrefPostsKey).(map[string]PostRef)
+	ambiguous, _ := pc.Get(xrefAmbiguousKey).(map[string][]string)
+	result, _ := pc.Get(xrefResultKey).(*xrefResult)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		link, ok := n.(*ast.Link)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		ref, ok := bytes.CutPrefix(link.Destination, []byte(xrefScheme))
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		slug, anchor, _ := strings.Cut(string(ref), "#")
+		target, found := posts[slug]
+		if result != nil {
+			switch {
+			case found:
+				result.targets = append(result.targets, target.URL)
+			case ambiguous[slug] != nil:
+				result.ambiguous = append(result.ambiguous, slug)
+			default:
+				result.unknown = append(result.unknown, slug)
+			}
+		}
+		if !found {
+			return ast.WalkContinue, nil
+		}
+
+		dest := target.URL
+		if anchor != "" {
+			dest += "#" + anchor
+		}
+		link.Destination = []byte(dest)
+		if !link.HasChildren() {
+			link.AppendChild(link, ast.NewString([]byte(target.Title)))
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// xrefTargets maps every post's RefKey to it, plus the bare slugs of posts
+// in other sections where exactly one section has the slug. Bare slugs
+// several sections share are returned with their candidates instead.
+func xrefTargets(posts []Post) (refs map[string]PostRef, ambiguous map[string][]string) {
+	refs = make(map[string]PostRef, len(posts))
+	for _, p := range posts {
+		refs[p.RefKey()] = PostRef{Title: p.Title, URL: p.URL()}
+	}
+	bare := make(map[string][]string)
+	for _, p := range posts {
+		if _, taken := refs[p.Slug]; !taken {
+			bare[p.Slug] = append(bare[p.Slug], p.RefKey())
+		}
+	}
+	ambiguous = make(map[string][]string)
+	for slug, keys := range bare {
+		if len(keys) == 1 {
+			refs[slug] = refs[keys[0]]
+		} else {
+			sort.Strings(keys)
+			ambiguous[slug] = keys
+		}
+	}
+	return refs, ambiguous
+}
+
+// addXrefFallback resolves the keys refs and ambiguous leave open against
+// the default-language entries, so a translation can link to a post that
+// has not been translated yet.
+func addXrefFallback(refs map[string]PostRef, ambiguous map[string][]string, fallback []Post) {
+	fallbackRefs, fallbackAmbiguous := xrefTargets(fallback)
+	for key, ref := range fallbackRefs {
+		if _, ok := refs[key]; !ok && ambiguous[key] == nil {
+			refs[key] = ref
+		}
+	}
+	for slug, keys := range fallbackAmbiguous {
+		if _, ok := refs[slug]; !ok && ambiguous[slug] == nil {
+			ambiguous[slug] = keys
+		}
+	}
+}
+
+// convertPosts renders every post's markdown once all posts are known, so
+// cross-references can be resolved, and fills in backlinks. Slugs none of
+// posts has are looked up in fallback, the default-language entries when
+// posts are a translation; unknown and ambiguous slugs fail the build.
+func (g *Generator) convertPosts(posts, fallback []Post) error {
+	refs, ambiguous := xrefTargets(posts)
+	if len(fallback) > 0 {
+		addXrefFallback(refs, ambiguous, fallback)
+	}
+
+	backlinks := make(map[string][]PostRef)
+	for i := range posts {
+		p := &posts[i]
+		result := &xrefResult{}
+		expander := &shortcodeExpander{g: g, post: p, newContext: func() parser.Context {
+			pc := parser.NewContext()
+			pc.Set(sourceFileKey, p.File)
+			if p.BundleDir != "" {
+				pc.Set(bundleBaseKey, p.ResourceURL())
+			}
+			pc.Set(xrefPostsKey, refs)
+			pc.Set(xrefAmbiguousKey, ambiguous)
+			pc.Set(xrefResultKey, result)
+			return pc
+		}}
+		html, err := expander.render(p.body, p.bodyLine)
+		if err != nil {
+			return err
+		}
+		p.Content = template.HTML(html)
+
+		for _, slug := range result.unknown {
+			g.diags.Errorf(p.File, 0, "未知的文章引用 %s", slug)
+		}
+		for _, slug := range result.ambiguous {
+			g.diags.Errorf(p.File, 0, "文章引用 %s 有歧义, 请写成 %s 之一", slug, strings.Join(ambiguous[slug], "、"))
+		}
+		seen := make(map[string]bool)
+		for _, url := range result.targets {
+			if seen[url] || url == p.URL() {
+				continue
+			}
+			seen[url] = true
+			backlinks[url] = append(backlinks[url], refs[p.RefKey()])
+		}
+	}
+
+	for i := range posts {
+		posts[i].Backlinks = backlinks[posts[i].URL()]
+	}
+	return nil
+}
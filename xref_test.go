@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestXrefTargets(t *testing.T) {
+	posts := []Post{
+		{Title: "Redis", Slug: "redis", Section: postsSection, Kind: kindPost, url: "/posts/redis.html"},
+		{Title: "Notes redis", Slug: "redis", Section: "notes", Kind: kindPost, url: "/notes/redis.html"},
+		{Title: "Only", Slug: "only", Section: "notes", Kind: kindPost, url: "/notes/only.html"},
+		{Title: "Dup A", Slug: "dup", Section: "notes", Kind: kindPost, url: "/notes/dup.html"},
+		{Title: "Dup B", Slug: "dup", Section: "talks", Kind: kindPost, url: "/talks/dup.html"},
+	}
+	refs, ambiguous := xrefTargets(posts)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"redis", "/posts/redis.html"},
+		{"notes/redis", "/notes/redis.html"},
+		{"only", "/notes/only.html"},
+		{"notes/dup", "/notes/dup.html"},
+		{"talks/dup", "/talks/dup.html"},
+		{"dup", ""},
+	}
+	for _, tt := range tests {
+		if got := refs[tt.key].URL; got != tt.want {
+			t.Errorf("refs[%q] = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	want := map[string][]string{"dup": {"notes/dup", "talks/dup"}}
+	if !reflect.DeepEqual(ambiguous, want) {
+		t.Errorf("ambiguous = %v, want %v", ambiguous, want)
+	}
+}
+
+func TestAddXrefFallback(t *testing.T) {
+	posts := []Post{
+		{Title: "Redis", Slug: "redis", Section: postsSection, Kind: kindPost, url: "/en/posts/redis.html"},
+	}
+	fallback := []Post{
+		{Title: "Redis zh", Slug: "redis", Section: postsSection, Kind: kindPost, url: "/posts/redis.html"},
+		{Title: "DB", Slug: "db", Section: postsSection, Kind: kindPost, url: "/posts/db.html"},
+		{Title: "Dup A", Slug: "dup", Section: "notes", Kind: kindPost, url: "/notes/dup.html"},
+		{Title: "Dup B", Slug: "dup", Section: "talks", Kind: kindPost, url: "/talks/dup.html"},
+	}
+	refs, ambiguous := xrefTargets(posts)
+	addXrefFallback(refs, ambiguous, fallback)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"redis", "/en/posts/redis.html"},
+		{"db", "/posts/db.html"},
+		{"notes/dup", "/notes/dup.html"},
+		{"dup", ""},
+	}
+	for _, tt := range tests {
+		if got := refs[tt.key].URL; got != tt.want {
+			t.Errorf("refs[%q] = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+	want := map[string][]string{"dup": {"notes/dup", "talks/dup"}}
+	if !reflect.DeepEqual(ambiguous, want) {
+		t.Errorf("ambiguous = %v, want %v", ambiguous, want)
+	}
+}